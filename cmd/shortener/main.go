@@ -2,12 +2,10 @@ package main
 
 import (
 	"context"
-	"errors"
 	stdlog "log"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/app"
@@ -29,28 +27,32 @@ func main() {
 		log.Fatal(ctx, err).Msg("Init app")
 	}
 
+	log.Info(ctx).Msg("Starting app...")
+	runErrs := make(chan error, 1)
 	go func() {
-		log.Info(ctx).Msg("Starting app...")
-		err := appl.Run(ctx)
-		if err != nil {
-			log.Fatal(ctx, err).Msg("Run app")
-		}
+		runErrs <- appl.Run(ctx)
 	}()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-quit
-	log.Info(ctx).Msgf("OS signal received: %s", sig)
 
-	time.AfterFunc(cfg.App.ShutdownTimeout, func() {
-		log.Fatal(ctx, errors.New("shutdown timeout")).Msg("App force-stopped")
-	})
+	exitCode := 0
+	select {
+	case sig := <-quit:
+		log.Info(ctx).Msgf("OS signal received: %s", sig)
+	case err := <-runErrs:
+		// A server failed to start or crashed, still shut down the rest properly
+		log.Error(ctx, err).Msg("Run app")
+		exitCode = 1
+	}
 
 	log.Info(ctx).Msg("Stopping app...")
 	err = appl.Stop(ctx)
 	if err != nil {
-		log.Fatal(ctx, err).Msg("Stop app")
+		log.Error(ctx, err).Msg("Stop app")
+		exitCode = 1
 	}
 
 	log.Info(ctx).Msg("App stopped")
+	os.Exit(exitCode)
 }
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	stdlog "log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/storage"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+const (
+	// importBatchSize is how many links are read into memory at once by import and copy
+	importBatchSize = 1000
+	// listPageSize is how many links of the user are read at once by list
+	listPageSize = 1000
+)
+
+var errUsage = errors.New("invalid arguments")
+
+type command struct {
+	args   string
+	help   string
+	writes bool
+	run    func(ctx context.Context, cli *ctl, args []string) error
+}
+
+var commands = map[string]command{
+	"list":     {args: "<user_uid>", help: "list links of a user, deleted ones included", run: listLinks},
+	"get":      {args: "<link_uid>", help: "show a link", run: getLink},
+	"delete":   {args: "<user_uid> <link_uid>...", help: "soft-delete links of a user", writes: true, run: deleteLinks},
+	"undelete": {args: "<user_uid> <link_uid>...", help: "undo soft-delete of links of a user", writes: true, run: undeleteLinks},
+	"export":   {args: "<file>", help: "write all links to a JSON backup file", run: exportLinks},
+	"import":   {args: "<file>", help: "read links from a JSON backup file, skipping existing ones", writes: true, run: importLinks},
+	"copy":     {args: "<backend>", help: "copy all links into another backend (" + strings.Join(repository.Backends(), "|") + ")", run: copyLinks},
+}
+
+type ctl struct {
+	cfg  *config.Config
+	repo repository.ShortlinkRepo
+	log  *logger.Logger
+}
+
+func main() {
+	ctx := context.Background()
+
+	flag.Usage = usage
+
+	// Same flags and env vars as the server, the command goes after the flags
+	cfg, err := config.Load()
+	if err != nil {
+		stdlog.Fatalf("Error loading config: %s", err)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	// Logs go to stderr, so that stdout only has the command output
+	log := logger.NewZerologLogger(ctx, "ctl", cfg.Logger.Level, cfg.Logger.Pretty, os.Stderr)
+
+	repo, err := openRepo(ctx, cfg.Storage.Backend, cfg, log)
+	if err != nil {
+		log.Fatal(ctx, err).Msgf("Open %s storage backend", cfg.Storage.Backend)
+	}
+
+	err = cmd.run(ctx, &ctl{cfg: cfg, repo: repo, log: log}, args[1:])
+	if err == nil && cmd.writes {
+		err = repo.Backup(ctx)
+	}
+	if closeErr := repo.Close(ctx); closeErr != nil {
+		log.Error(ctx, closeErr).Msg("Close storage backend")
+	}
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "Usage: shortenerctl [flags] %s %s\n", args[0], cmd.args)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(ctx, err).Msgf("Run %s", args[0])
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: shortenerctl [flags] <command> [args]\n\nCommands:\n")
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, name := range []string{"list", "get", "delete", "undelete", "export", "import", "copy"} {
+		cmd := commands[name]
+		fmt.Fprintf(tw, "  %s %s\t%s\n", name, cmd.args, cmd.help)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// openRepo opens a backend the same way the server does, including the restore from backup
+func openRepo(ctx context.Context, name string, cfg *config.Config, log *logger.Logger) (repository.ShortlinkRepo, error) {
+	backend, err := repository.NewBackend(ctx, name, cfg, log.SubLogger(name))
+	if err != nil {
+		return nil, err
+	}
+
+	err = backend.Shortlinks.Restore(ctx)
+	if err != nil {
+		if closeErr := backend.Shortlinks.Close(ctx); closeErr != nil {
+			log.Error(ctx, closeErr).Msg("close storage backend")
+		}
+		return nil, log.Wrap(err, "restore from backup")
+	}
+
+	return backend.Shortlinks, nil
+}
+
+func listLinks(ctx context.Context, cli *ctl, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	userUID := args[0]
+
+	tw := newTable()
+
+	// Only the user's links are read, page by page, deleted ones come from the trash afterwards
+	query := entity.ShortlinkQuery{Sort: entity.ShortlinkSortCreated, Limit: listPageSize}
+	for {
+		page, err := cli.repo.GetShortlinks(ctx, userUID, query)
+		if err != nil {
+			return err
+		}
+		for _, link := range page.Links {
+			printLink(tw, link)
+		}
+		if page.Next == nil {
+			break
+		}
+		query.After = page.Next
+	}
+
+	deleted, err := cli.repo.GetDeletedShortlinks(ctx, userUID)
+	if err != nil {
+		return err
+	}
+	for _, link := range deleted {
+		printLink(tw, link)
+	}
+	return tw.Flush()
+}
+
+func getLink(ctx context.Context, cli *ctl, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	link, err := cli.repo.FindShortlink(ctx, "", args[0])
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return fmt.Errorf("link %s not found", args[0])
+	}
+
+	tw := newTable()
+	printLink(tw, link)
+	return tw.Flush()
+}
+
+func deleteLinks(ctx context.Context, cli *ctl, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	deleted, err := cli.repo.DeleteShortlinks(ctx, args[0], args[1:])
+	if err != nil {
+		return err
+	}
+
+	cli.log.Info(ctx).Msgf("Deleted %d links", len(deleted))
+	return nil
+}
+
+func undeleteLinks(ctx context.Context, cli *ctl, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	restored, err := cli.repo.UndeleteShortlinks(ctx, args[0], args[1:])
+	if err != nil {
+		return err
+	}
+
+	cli.log.Info(ctx).Msgf("Restored %d links", len(restored))
+	return nil
+}
+
+func exportLinks(ctx context.Context, cli *ctl, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	file, err := storage.NewFileStorage(args[0])
+	if err != nil {
+		return cli.log.Wrap(err, "open export file")
+	}
+	defer file.Close(ctx)
+
+	var exported int
+	err = file.BackupEach(ctx, func(yield func(link *entity.Shortlink) error) error {
+		return cli.repo.IterateShortlinks(ctx, func(link *entity.Shortlink) error {
+			exported++
+			return yield(link)
+		})
+	})
+	if err != nil {
+		return cli.log.Wrap(err, "export")
+	}
+
+	cli.log.Info(ctx).Msgf("Exported %d links to %s", exported, args[0])
+	return nil
+}
+
+func importLinks(ctx context.Context, cli *ctl, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	// FileStorage creates missing files, which is not what a typo in the path should do
+	_, err := os.Stat(args[0])
+	if err != nil {
+		return cli.log.Wrap(err, "open import file")
+	}
+
+	file, err := storage.NewFileStorage(args[0])
+	if err != nil {
+		return cli.log.Wrap(err, "open import file")
+	}
+	defer file.Close(ctx)
+
+	read, imported, err := importEach(ctx, cli.repo, func(fn func(link *entity.Shortlink) error) error {
+		return file.RestoreEach(ctx, fn)
+	})
+	if err != nil {
+		return cli.log.Wrap(err, "import")
+	}
+
+	cli.log.Info(ctx).Msgf("Imported %d of %d links from %s", imported, read, args[0])
+	return nil
+}
+
+func copyLinks(ctx context.Context, cli *ctl, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	name := args[0]
+	if name == cli.cfg.Storage.Backend {
+		return fmt.Errorf("source and target backends are the same (%s)", name)
+	}
+
+	target, err := openRepo(ctx, name, cli.cfg, cli.log)
+	if err != nil {
+		return cli.log.Wrapf(err, "open %s storage backend", name)
+	}
+
+	start := time.Now()
+	read, imported, err := importEach(ctx, target, func(fn func(link *entity.Shortlink) error) error {
+		return cli.repo.IterateShortlinks(ctx, fn)
+	})
+	if err == nil {
+		// Target may only be persisted on backup (e.g. file backend in snapshot mode)
+		err = target.Backup(ctx)
+	}
+	if closeErr := target.Close(ctx); closeErr != nil {
+		cli.log.Error(ctx, closeErr).Msgf("close %s storage backend", name)
+	}
+	if err != nil {
+		return cli.log.Wrapf(err, "copy to %s", name)
+	}
+
+	cli.log.Info(ctx).Msgf("Copied %d of %d links to %s in %s", imported, read, name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// importEach saves links produced by iterate into repo in batches, returns the number of links read and imported
+func importEach(ctx context.Context, repo repository.ShortlinkRepo, iterate func(fn func(link *entity.Shortlink) error) error) (int64, int64, error) {
+	var read, imported int64
+	batch := make([]*entity.Shortlink, 0, importBatchSize)
+
+	flush := func() error {
+		inserted, err := repo.ImportShortlinks(ctx, batch)
+		imported += inserted
+		batch = batch[:0]
+		return err
+	}
+
+	err := iterate(func(link *entity.Shortlink) error {
+		read++
+		batch = append(batch, link)
+		if len(batch) < importBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	return read, imported, err
+}
+
+func newTable() *tabwriter.Writer {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSER\tDELETED\tEXPIRES\tCORRELATION\tURL")
+	return tw
+}
+
+func printLink(tw *tabwriter.Writer, link *entity.Shortlink) {
+	expires := "-"
+	if link.ExpiresAt != nil {
+		expires = link.ExpiresAt.Format(time.RFC3339)
+	}
+	correlationID := link.CorrelationID
+	if correlationID == "" {
+		correlationID = "-"
+	}
+	fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\t%s\n", link.UID, link.UserUID, link.Deleted, expires, correlationID, link.Long)
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+type (
+	// Lifecycle runs shutdown phases in the order they were added, each one with its own timeout.
+	// A failed or timed out phase is logged and does not stop the following ones,
+	// e.g. storage is still closed if the backup fails
+	Lifecycle struct {
+		phases []phase
+		log    *logger.Logger
+	}
+	phase struct {
+		name    string
+		timeout time.Duration
+		stop    func(ctx context.Context) error
+	}
+)
+
+func NewLifecycle(log *logger.Logger) *Lifecycle {
+	return &Lifecycle{
+		log: log,
+	}
+}
+
+// OnStop adds a phase, zero timeout means the phase is only limited by the Stop context
+func (l *Lifecycle) OnStop(name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	l.phases = append(l.phases, phase{
+		name:    name,
+		timeout: timeout,
+		stop:    stop,
+	})
+}
+
+// Stop runs all phases and returns the joined errors of the failed ones
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	var errs []error
+
+	for _, p := range l.phases {
+		start := time.Now()
+
+		err := l.run(ctx, p)
+		if err != nil {
+			l.log.Error(ctx, err).Msgf("Shutdown phase %s failed after %s", p.name, time.Since(start).Round(time.Millisecond))
+			errs = append(errs, l.log.Wrap(err, p.name))
+			continue
+		}
+		l.log.Info(ctx).Msgf("Shutdown phase %s done in %s", p.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l *Lifecycle) run(ctx context.Context, p phase) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	// A phase that ignores ctx must not hold up the rest of the shutdown
+	done := make(chan error, 1)
+	go func() {
+		done <- p.stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
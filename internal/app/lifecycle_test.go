@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestLifecycle(t *testing.T) {
+	errBackup := errors.New("disk full")
+
+	type phase struct {
+		name    string
+		timeout time.Duration
+		err     error
+		// hang ignores ctx and never returns
+		hang bool
+	}
+	tests := []struct {
+		name    string
+		phases  []phase
+		wantRun []string
+		wantErr []error
+	}{
+		{
+			name: "all phases in order",
+			phases: []phase{
+				{name: "server"},
+				{name: "backup"},
+				{name: "storage"},
+			},
+			wantRun: []string{"server", "backup", "storage"},
+		},
+		{
+			name: "failed phase does not stop the rest",
+			phases: []phase{
+				{name: "server"},
+				{name: "backup", err: errBackup},
+				{name: "storage"},
+			},
+			wantRun: []string{"server", "backup", "storage"},
+			wantErr: []error{errBackup},
+		},
+		{
+			name: "phase ignoring its timeout",
+			phases: []phase{
+				{name: "server", timeout: 20 * time.Millisecond, hang: true},
+				{name: "backup", err: errBackup},
+				{name: "storage"},
+			},
+			wantRun: []string{"server", "backup", "storage"},
+			wantErr: []error{context.DeadlineExceeded, errBackup},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := NewLifecycle(logger.NewMockLogger())
+
+			var run []string
+			var mutex sync.Mutex
+			for _, p := range tt.phases {
+				p := p
+				lc.OnStop(p.name, p.timeout, func(ctx context.Context) error {
+					mutex.Lock()
+					run = append(run, p.name)
+					mutex.Unlock()
+					if p.hang {
+						select {}
+					}
+					return p.err
+				})
+			}
+
+			err := lc.Stop(context.Background())
+			mutex.Lock()
+			assert.Equal(t, tt.wantRun, run)
+			mutex.Unlock()
+
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+			}
+			for _, wantErr := range tt.wantErr {
+				assert.ErrorIs(t, err, wantErr)
+			}
+		})
+	}
+}
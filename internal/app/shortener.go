@@ -3,52 +3,88 @@ package app
 import (
 	"context"
 	"errors"
+	"net"
 	nethttp "net/http"
+	"time"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	googlegrpc "google.golang.org/grpc"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/grpc"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/grpc/interceptor"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/http"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/http/middleware"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/crypto"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/health"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/linkid"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository/batch"
-	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/storage"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/tracing"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/usecase"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
 )
 
 type Shortener struct {
-	server *nethttp.Server
-	repo   repository.ShortlinkRepo
-	log    *logger.Logger
+	server     *nethttp.Server
+	grpcServer *googlegrpc.Server
+	grpcAddr   string
+	repo       repository.ShortlinkRepo
+	tracing    *tracing.Provider
+	health     *health.Checker
+	drainDelay time.Duration
+	lifecycle  *Lifecycle
+	log        *logger.Logger
 }
 
-func NewShortener(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Shortener, error) {
-	app := &Shortener{
-		log: log,
-	}
-
+// newRouter serves the metrics and health probes to anyone, and returns the group for the API routes,
+// which identify the user by the auth cookie (a new user is made up for a request without one)
+func newRouter(cipher crypto.EncryptorDecryptor, checker *health.Checker, log *logger.Logger) (*gin.Engine, gin.IRouter) {
 	handler := gin.New()
 	handler.ContextWithFallback = true
 
 	handler.Use(gin.Recovery())
 	handler.Use(gzip.Gzip(gzip.BestSpeed, gzip.WithDecompressFn(gzip.DefaultDecompressHandle)))
 
+	handler.Use(middleware.RequestID(log))
+	handler.Use(middleware.Tracing(log))
+	handler.Use(middleware.Logger(log.SubLogger("http_requests")))
+	handler.Use(middleware.Metrics())
+
+	handler.GET("/metrics", metrics.Handler())
+	http.NewHealthController(handler, checker, log.SubLogger("health_controller"))
+
+	api := handler.Group("/", middleware.CookieAuth(middleware.CookieAuthConfig{
+		Cipher: cipher,
+	}, log))
+
+	return handler, api
+}
+
+func NewShortener(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Shortener, error) {
+	app := &Shortener{
+		grpcAddr:   cfg.GRPC.Addr,
+		health:     health.NewChecker(cfg.App.ReadinessTimeout),
+		drainDelay: cfg.Shutdown.DrainDelay,
+		lifecycle:  NewLifecycle(log.SubLogger("lifecycle")),
+		log:        log,
+	}
+
+	var err error
+	app.tracing, err = tracing.Init(ctx, cfg.Tracing, log.SubLogger("tracing"))
+	if err != nil {
+		return nil, log.Wrap(err, "init tracing")
+	}
+	log.Info(ctx).Msgf("Initialized tracing @ %s", cfg.Tracing.Exporter)
+
 	cipher, err := crypto.NewAES256(cfg.App.AuthSecret, log)
 	if err != nil {
 		return nil, log.Wrap(err, "init crypto cipher")
 	}
 
-	authMiddleware := middleware.CookieAuth(middleware.CookieAuthConfig{
-		Cipher: cipher,
-	}, log)
-
-	handler.Use(authMiddleware)
-
-	handler.Use(middleware.RequestID(log))
-	handler.Use(middleware.Logger(log.SubLogger("http_requests")))
+	handler, api := newRouter(cipher, app.health, log)
 
 	handler.Handler()
 	app.server = &nethttp.Server{
@@ -58,22 +94,39 @@ func NewShortener(ctx context.Context, cfg *config.Config, log *logger.Logger) (
 		Addr: cfg.Server.Addr,
 	}
 
-	backup, err := storage.NewFileStorage(cfg.Storage.Filepath)
-	if err != nil {
-		return nil, log.Wrap(err, "init backup storage")
-	}
-	log.Info(ctx).Msgf("Initialized backup storage @ %s", cfg.Storage.Filepath)
+	app.grpcServer = googlegrpc.NewServer(googlegrpc.ChainUnaryInterceptor(
+		interceptor.Tracing(),
+		interceptor.Logger(log.SubLogger("grpc_requests")),
+		interceptor.MetadataAuth(interceptor.MetadataAuthConfig{
+			Cipher: cipher,
+		}, log),
+	))
 
-	var shortlinkRepo repository.ShortlinkRepo
-	shortlinkRepo, err = repository.NewPostgresRepo(ctx, cfg.PostgreSQL, backup, log.SubLogger("shortlink_repo"))
+	backend, err := repository.NewBackend(ctx, cfg.Storage.Backend, cfg, log.SubLogger("shortlink_repo"))
 	if err != nil {
-		log.Error(ctx, err).Msg("init shortlink repo")
-		// Fallback to in-mem repo
-		shortlinkRepo = repository.NewInMemShortlinkRepo(backup)
-		log.Info(ctx).Msgf("Initialized shortlink repo @ in-mem")
+		if cfg.Storage.Fallback == "" {
+			return nil, log.Wrapf(err, "init %s storage backend", cfg.Storage.Backend)
+		}
+		log.Error(ctx, err).Msgf("init %s storage backend, falling back to %s", cfg.Storage.Backend, cfg.Storage.Fallback)
+
+		backend, err = repository.NewBackend(ctx, cfg.Storage.Fallback, cfg, log.SubLogger("shortlink_repo"))
+		if err != nil {
+			return nil, log.Wrapf(err, "init %s fallback storage backend", cfg.Storage.Fallback)
+		}
+		log.Warn(ctx).Msgf("Initialized shortlink repo @ %s (fallback)", cfg.Storage.Fallback)
 	} else {
-		log.Info(ctx).Msgf("Initialized shortlink repo @ %s", cfg.PostgreSQL.ConnString)
+		log.Info(ctx).Msgf("Initialized shortlink repo @ %s", cfg.Storage.Backend)
 	}
+	var shortlinkRepo repository.ShortlinkRepo = repository.NewMetricsShortlinkRepo(backend.Shortlinks)
+	var cache *repository.CachedShortlinkRepo
+	if cfg.Cache.Size > 0 {
+		// In front of the metrics, so that those show the backend load
+		cache = repository.NewCachedShortlinkRepo(shortlinkRepo, cfg.Cache)
+		shortlinkRepo = cache
+		log.Info(ctx).Msgf("Initialized shortlink cache of %d links", cfg.Cache.Size)
+	}
+	clickRepo := repository.NewMetricsClickRepo(backend.Clicks)
+	deleteQueue := repository.NewMetricsDeleteQueueRepo(backend.DeleteQueue)
 	app.repo = shortlinkRepo
 
 	err = shortlinkRepo.Restore(ctx)
@@ -82,37 +135,154 @@ func NewShortener(ctx context.Context, cfg *config.Config, log *logger.Logger) (
 	}
 	log.Info(ctx).Msgf("Restore from backup complete")
 
-	batchProcessor := batch.NewProcessor(ctx, shortlinkRepo, log.SubLogger("batch_processor"))
+	batchProcessor := batch.NewProcessor(ctx, cfg.Batch, shortlinkRepo, clickRepo, deleteQueue, log.SubLogger("batch_processor"))
+
+	app.health.Register("repository", shortlinkRepo.Ping)
+	app.health.Register("backup_storage", backend.Storage.Ping)
+	app.health.Register("batch_processor", batchProcessor.Ping)
 
-	shortenerUC := usecase.NewShortener(cfg.Shortener, shortlinkRepo, batchProcessor, log.SubLogger("shortener_uc"))
-	http.NewShortenerController(handler, shortenerUC, log.SubLogger("shortener_controller"))
+	idSequence := backend.IDSequence
+	if idSequence == nil {
+		// Numbers below the count are mostly taken already, the rest are retried by the usecase on conflict
+		count, err := shortlinkRepo.CountShortlinks(ctx)
+		if err != nil {
+			return nil, log.Wrap(err, "count shortlinks for link ID sequence")
+		}
+		idSequence = linkid.NewInMemSequence(uint64(count))
+	}
+	idGenerator, err := linkid.New(cfg.Shortener, idSequence)
+	if err != nil {
+		return nil, log.Wrapf(err, "init %s link ID generator", cfg.Shortener.IDStrategy)
+	}
+
+	idLength, err := linkid.NewAdaptiveLength(cfg.Shortener, shortlinkRepo.CountShortlinks, log.SubLogger("link_id_length"))
+	if err != nil {
+		return nil, log.Wrap(err, "init link ID length")
+	}
+	err = idLength.Refresh(ctx)
+	if err != nil {
+		return nil, log.Wrap(err, "refresh link ID length")
+	}
+	refreshCtx, stopRefresh := context.WithCancel(ctx)
+	go idLength.Run(refreshCtx, cfg.Shortener.IDRefreshInterval)
+
+	// Other instances sharing the backend tell which links they changed
+	invalidatorCtx, stopInvalidator := context.WithCancel(ctx)
+	if cache != nil && backend.Notifications != nil {
+		invalidator := repository.NewCacheInvalidator(cache, backend.Notifications, cfg.Cache, log.SubLogger("cache_invalidator"))
+		go invalidator.Run(invalidatorCtx)
+	}
+
+	shortenerUC := usecase.NewShortener(cfg.Shortener, idGenerator, idLength, shortlinkRepo, clickRepo, batchProcessor, log.SubLogger("shortener_uc"))
+	http.NewShortenerController(api, shortenerUC, log.SubLogger("shortener_controller"))
+	grpc.NewShortenerController(app.grpcServer, shortenerUC, log.SubLogger("shortener_grpc_controller"))
+
+	// Traffic is drained first, storage is closed last
+	app.lifecycle.OnStop("drain", 0, app.drain)
+	app.lifecycle.OnStop("http server", cfg.Shutdown.ServerTimeout, app.stopHTTP)
+	app.lifecycle.OnStop("grpc server", cfg.Shutdown.ServerTimeout, app.stopGRPC)
+	app.lifecycle.OnStop("batch processor", cfg.Shutdown.FlushTimeout, batchProcessor.Stop)
+	app.lifecycle.OnStop("link ID length", 0, func(ctx context.Context) error {
+		stopRefresh()
+		return nil
+	})
+	app.lifecycle.OnStop("cache invalidator", 0, func(ctx context.Context) error {
+		stopInvalidator()
+		return nil
+	})
+	app.lifecycle.OnStop("backup", cfg.Shutdown.BackupTimeout, shortlinkRepo.Backup)
+	app.lifecycle.OnStop("storage", cfg.Shutdown.CloseTimeout, shortlinkRepo.Close)
+	app.lifecycle.OnStop("tracing", cfg.Shutdown.CloseTimeout, app.tracing.Shutdown)
 
 	return app, nil
 }
 
 func (a *Shortener) Run(ctx context.Context) error {
+	errs := make(chan error, 2)
+
+	go func() {
+		errs <- a.runHTTP(ctx)
+	}()
+	go func() {
+		errs <- a.runGRPC(ctx)
+	}()
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Shortener) runHTTP(ctx context.Context) error {
 	a.log.Info(ctx).Msgf("Listening on %s", a.server.Addr)
 	if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
-		return err
+		return a.log.Wrap(err, "serve http")
 	}
 	return nil
 }
 
-func (a *Shortener) Stop(ctx context.Context) error {
-	err := a.repo.Backup(ctx)
-	if err != nil {
-		return a.log.Wrap(err, "backup repo")
+func (a *Shortener) runGRPC(ctx context.Context) error {
+	if a.grpcAddr == "" {
+		return nil
 	}
 
-	err = a.repo.Close(ctx)
+	listener, err := net.Listen("tcp", a.grpcAddr)
 	if err != nil {
-		return a.log.Wrap(err, "close repo")
+		return a.log.Wrap(err, "listen grpc")
 	}
 
-	err = a.server.Shutdown(ctx)
+	a.log.Info(ctx).Msgf("Listening (gRPC) on %s", a.grpcAddr)
+	if err := a.grpcServer.Serve(listener); err != nil && !errors.Is(err, googlegrpc.ErrServerStopped) {
+		return a.log.Wrap(err, "serve grpc")
+	}
+	return nil
+}
+
+// Stop runs the shutdown phases, failed phases are logged and returned together at the end
+func (a *Shortener) Stop(ctx context.Context) error {
+	return a.lifecycle.Stop(ctx)
+}
+
+// drain fails readiness, and gives load balancers time to notice before the servers stop
+func (a *Shortener) drain(ctx context.Context) error {
+	a.health.Shutdown()
+	if a.drainDelay <= 0 {
+		return nil
+	}
+
+	a.log.Info(ctx).Msgf("Not ready, draining traffic for %s", a.drainDelay)
+	select {
+	case <-time.After(a.drainDelay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopHTTP waits for in-flight requests to finish, unless ctx is done first
+func (a *Shortener) stopHTTP(ctx context.Context) error {
+	err := a.server.Shutdown(ctx)
 	if err != nil {
-		return a.log.Wrap(err, "shutdown server")
+		_ = a.server.Close()
 	}
+	return err
+}
 
-	return nil
+// stopGRPC waits for in-flight RPCs to finish, unless ctx is done first
+func (a *Shortener) stopGRPC(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		a.grpcServer.Stop()
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/http/middleware"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/crypto"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/health"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestRouterAuth(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	handler, api := newRouter(crypto.NewMock(), health.NewChecker(time.Second), logger.NewMockLogger())
+	api.GET("/api/user/urls", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantCookie bool
+	}{
+		{
+			name:       "metrics scrape is not a user",
+			path:       "/metrics",
+			wantCookie: false,
+		},
+		{
+			name:       "liveness probe is not a user",
+			path:       "/healthz",
+			wantCookie: false,
+		},
+		{
+			name:       "readiness probe is not a user",
+			path:       "/readyz",
+			wantCookie: false,
+		},
+		{
+			name:       "api request is",
+			path:       "/api/user/urls",
+			wantCookie: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var names []string
+			for _, cookie := range w.Result().Cookies() {
+				names = append(names, cookie.Name)
+			}
+			if tt.wantCookie {
+				assert.Contains(t, names, middleware.CookieAuthName)
+			} else {
+				assert.NotContains(t, names, middleware.CookieAuthName)
+			}
+		})
+	}
+}
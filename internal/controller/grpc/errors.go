@@ -0,0 +1,7 @@
+package grpc
+
+import "errors"
+
+var (
+	ErrUnauthenticatedUser = errors.New("user is not authenticated")
+)
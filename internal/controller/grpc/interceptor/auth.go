@@ -0,0 +1,77 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/crypto"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+// MetadataAuthName is the gRPC counterpart of the Authorization-Token cookie
+const MetadataAuthName = "authorization-token"
+
+type (
+	MetadataAuthConfig struct {
+		Cipher crypto.EncryptorDecryptor
+	}
+)
+
+func MetadataAuth(cfg MetadataAuthConfig, log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var token *entity.AuthToken
+		var err error
+
+		// Get token from metadata
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(MetadataAuthName); len(values) > 0 && values[0] != "" {
+				token, _ = cfg.Cipher.Decrypt(ctx, values[0])
+			}
+		}
+		if token == nil {
+			// Generate new
+			token, err = generateToken()
+			if err != nil {
+				log.Error(ctx, err).Msg("generate metadata-auth token")
+				return nil, status.Error(codes.Internal, "generate auth token")
+			}
+		}
+
+		// Encrypt the token
+		encrypted, err := cfg.Cipher.Encrypt(ctx, token)
+		if err != nil {
+			log.Error(ctx, err).Msg("encrypt metadata-auth token")
+			return nil, status.Error(codes.Internal, "encrypt auth token")
+		}
+
+		// Send encrypted token back in the response header
+		err = grpc.SetHeader(ctx, metadata.Pairs(MetadataAuthName, encrypted))
+		if err != nil {
+			log.Error(ctx, err).Msg("set metadata-auth header")
+		}
+
+		// Add token to request context and go to next interceptor/handler
+		return handler(context.WithValue(ctx, entity.AuthTokenCtxKey, token), req)
+	}
+}
+
+func generateToken() (*entity.AuthToken, error) {
+	// Generate random bytes for the user UID
+	userUID := make([]byte, 16)
+	_, err := rand.Read(userUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare random bytes for userUID")
+	}
+
+	return &entity.AuthToken{
+		UserUID: hex.EncodeToString(userUID),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func Logger(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		log.Info(ctx).
+			Str("method", info.FullMethod).
+			Msg("-> New request")
+
+		// Go to next interceptor/handler
+		resp, err := handler(ctx, req)
+
+		// Different log level depending on status code
+		var msg *zerolog.Event
+		code := status.Code(err)
+		switch code {
+		case codes.OK:
+			msg = log.Info(ctx)
+		case codes.Internal, codes.Unknown, codes.Unavailable:
+			msg = log.Error(ctx, err)
+		default:
+			msg = log.Warn(ctx)
+		}
+
+		msg.Str("method", info.FullMethod).
+			Str("code", code.String()).
+			Msg("<- Request handled")
+
+		return resp, err
+	}
+}
@@ -0,0 +1,65 @@
+package interceptor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/tracing"
+)
+
+func Tracing() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		ctx, span := tracing.StartServer(ctx, info.FullMethod, metadataCarrier(md),
+			semconv.RPCSystemGRPC,
+			attribute.String("rpc.method", info.FullMethod),
+		)
+		defer span.End()
+
+		// Let the client find the trace of its request
+		out := metadata.MD{}
+		tracing.Inject(ctx, metadataCarrier(out))
+		_ = grpc.SetHeader(ctx, out)
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if code == codes.Internal || code == codes.Unknown || code == codes.Unavailable {
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+
+		return resp, err
+	}
+}
+
+// metadataCarrier adapts gRPC metadata for the W3C propagator
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
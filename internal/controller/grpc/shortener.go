@@ -0,0 +1,271 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/grpc/pb"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/usecase"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+type ShortenerController struct {
+	pb.UnimplementedShortenerServer
+
+	shortener usecase.Shortener
+
+	log *logger.Logger
+}
+
+func NewShortenerController(server *grpc.Server, shortener usecase.Shortener, log *logger.Logger) *ShortenerController {
+	c := &ShortenerController{
+		shortener: shortener,
+		log:       log,
+	}
+
+	pb.RegisterShortenerServer(server, c)
+
+	return c
+}
+
+func (ct *ShortenerController) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
+	err := ct.shortener.Ping(ctx)
+	if err != nil {
+		return nil, ct.errorStatus(err)
+	}
+
+	return &pb.PingResponse{}, nil
+}
+
+func (ct *ShortenerController) CreateShortlink(ctx context.Context, req *pb.CreateShortlinkRequest) (*pb.CreateShortlinkResponse, error) {
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	link, err := ct.shortener.CreateShortlink(ctx, usecase.CreateShortlinkIn{
+		UserUID:   userUID,
+		URL:       req.GetUrl(),
+		Alias:     req.GetAlias(),
+		ExpiresAt: timestampToTime(req.GetExpiresAt()),
+		TTL:       time.Duration(req.GetTtlSeconds()) * time.Second,
+	})
+
+	switch {
+	case err == nil:
+		return &pb.CreateShortlinkResponse{Shortlink: shortlinkToPB(link)}, nil
+	case errors.Is(err, repository.ErrURLConflict):
+		return &pb.CreateShortlinkResponse{Shortlink: shortlinkToPB(link), Conflict: true}, nil
+	default:
+		ct.log.Error(ctx, err).Msg("create shortlink")
+		return nil, ct.errorStatus(err)
+	}
+}
+
+func (ct *ShortenerController) CreateShortlinks(ctx context.Context, req *pb.CreateShortlinksRequest) (*pb.CreateShortlinksResponse, error) {
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	var data []usecase.CreateShortlinksInLink
+	for _, link := range req.GetLinks() {
+		data = append(data, usecase.CreateShortlinksInLink{
+			URL:           link.GetOriginalUrl(),
+			Alias:         link.GetAlias(),
+			ExpiresAt:     timestampToTime(link.GetExpiresAt()),
+			TTL:           time.Duration(link.GetTtlSeconds()) * time.Second,
+			CorrelationID: link.GetCorrelationId(),
+		})
+	}
+
+	links, err := ct.shortener.CreateShortlinks(ctx, usecase.CreateShortlinksIn{
+		UserUID: userUID,
+		Length:  0,
+		Links:   data,
+	})
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("create shortlinks")
+		return nil, ct.errorStatus(err)
+	}
+
+	result := &pb.CreateShortlinksResponse{}
+	for _, link := range links {
+		result.Shortlinks = append(result.Shortlinks, shortlinkToPB(link))
+	}
+
+	return result, nil
+}
+
+func (ct *ShortenerController) GetShortlink(ctx context.Context, req *pb.GetShortlinkRequest) (*pb.GetShortlinkResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	link, err := ct.shortener.GetShortlink(ctx, req.GetId())
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("get shortlink")
+		return nil, ct.errorStatus(err)
+	}
+	if link == nil {
+		return nil, status.Error(codes.NotFound, usecase.ErrShortlinkNotFound.Error())
+	}
+
+	return &pb.GetShortlinkResponse{Shortlink: shortlinkToPB(link)}, nil
+}
+
+func (ct *ShortenerController) ListUserShortlinks(ctx context.Context, req *pb.ListUserShortlinksRequest) (*pb.ListUserShortlinksResponse, error) {
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	query := entity.ShortlinkQuery{Limit: int(req.GetPageSize())}
+	if req.GetCursor() != "" {
+		query.After, err = entity.ParseShortlinkCursor(req.GetCursor())
+		if err != nil {
+			ct.log.Error(ctx, err).Msg("parse cursor")
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	page, err := ct.shortener.ListUserShortlinks(ctx, userUID, query)
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("list user shortlinks")
+		return nil, ct.errorStatus(err)
+	}
+
+	result := &pb.ListUserShortlinksResponse{}
+	for _, link := range page.Links {
+		result.Shortlinks = append(result.Shortlinks, shortlinkToPB(link))
+	}
+	if page.Next != nil {
+		result.Next = page.Next.String()
+	}
+
+	return result, nil
+}
+
+func (ct *ShortenerController) DeleteUserShortlinks(ctx context.Context, req *pb.DeleteUserShortlinksRequest) (*pb.DeleteUserShortlinksResponse, error) {
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	job, err := ct.shortener.DeleteUserShortlinks(ctx, userUID, req.GetIds())
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("delete user shortlinks")
+		return nil, ct.errorStatus(err)
+	}
+
+	return &pb.DeleteUserShortlinksResponse{JobId: job.ID}, nil
+}
+
+func (ct *ShortenerController) GetDeleteJob(ctx context.Context, req *pb.GetDeleteJobRequest) (*pb.GetDeleteJobResponse, error) {
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	job, err := ct.shortener.GetUserDeleteJob(ctx, userUID, req.GetId())
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("get user delete job")
+		return nil, ct.errorStatus(err)
+	}
+
+	result := &pb.DeleteJob{
+		Id:        job.ID,
+		State:     job.State(),
+		Requested: int32(len(job.LinkUIDs)),
+		Deleted:   int32(job.Deleted),
+		NotFound:  job.NotFound,
+		Attempts:  int32(job.Attempts),
+		LastError: job.LastError,
+		CreatedAt: timestamppb.New(job.CreatedAt),
+	}
+	if job.DoneAt != nil {
+		result.FinishedAt = timestamppb.New(*job.DoneAt)
+	} else if job.DeadAt != nil {
+		result.FinishedAt = timestamppb.New(*job.DeadAt)
+	}
+
+	return &pb.GetDeleteJobResponse{Job: result}, nil
+}
+
+func (ct *ShortenerController) userUID(ctx context.Context) (string, error) {
+	token, ok := ctx.Value(entity.AuthTokenCtxKey).(*entity.AuthToken)
+	if !ok || token.UserUID == "" {
+		return "", ErrUnauthenticatedUser
+	}
+
+	return token.UserUID, nil
+}
+
+func (ct *ShortenerController) errorStatus(err error) error {
+	var code codes.Code
+
+	switch {
+	case errors.Is(err, usecase.ErrInvalidURL):
+		fallthrough
+	case errors.Is(err, usecase.ErrIncompleteURL):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidAlias):
+		fallthrough
+	case errors.Is(err, usecase.ErrReservedAlias):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidExpiry):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidStatsQuery):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidListQuery):
+		fallthrough
+	case errors.Is(err, usecase.ErrNothingToDelete):
+		code = codes.InvalidArgument
+	case errors.Is(err, usecase.ErrShortlinkNotFound):
+		fallthrough
+	case errors.Is(err, usecase.ErrDeleteJobNotFound):
+		code = codes.NotFound
+	case errors.Is(err, usecase.ErrUIDConflict):
+		fallthrough
+	case errors.Is(err, usecase.ErrAliasConflict):
+		code = codes.AlreadyExists
+	case errors.Is(err, usecase.ErrTooManyDeletes):
+		code = codes.ResourceExhausted
+	case errors.Is(err, usecase.ErrDBUnavailable):
+		code = codes.Unavailable
+	default:
+		code = codes.Internal
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func shortlinkToPB(link *entity.Shortlink) *pb.Shortlink {
+	result := &pb.Shortlink{
+		Id:            link.UID,
+		ShortUrl:      link.Short,
+		OriginalUrl:   link.Long,
+		CorrelationId: link.CorrelationID,
+		Deleted:       link.Deleted,
+	}
+	if link.ExpiresAt != nil {
+		result.ExpiresAt = timestamppb.New(*link.ExpiresAt)
+	}
+	return result
+}
+
+func timestampToTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,215 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/grpc/interceptor"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/grpc/pb"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/crypto"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/linkid"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository/batch"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/usecase"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+const dummyUserID = "user1"
+
+var log = logger.NewMockLogger()
+
+func TestCreateShortlink(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   *pb.CreateShortlinkRequest
+		code  codes.Code
+		alias string
+	}{
+		{
+			name: "incomplete url",
+			req:  &pb.CreateShortlinkRequest{Url: "example.org"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "reserved alias",
+			req:  &pb.CreateShortlinkRequest{Url: "https://example.org", Alias: "api"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "ok",
+			req:  &pb.CreateShortlinkRequest{Url: "https://example.org"},
+			code: codes.OK,
+		},
+		{
+			name:  "ok with alias",
+			req:   &pb.CreateShortlinkRequest{Url: "https://example.org", Alias: "spring-sale"},
+			code:  codes.OK,
+			alias: "spring-sale",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := prepareClient(t)
+
+			resp, err := client.CreateShortlink(authContext(dummyUserID), tt.req)
+			require.Equal(t, tt.code, status.Code(err))
+
+			if tt.code == codes.OK {
+				assert.NotEmpty(t, resp.GetShortlink().GetShortUrl())
+				assert.Equal(t, tt.req.GetUrl(), resp.GetShortlink().GetOriginalUrl())
+				if tt.alias != "" {
+					assert.Equal(t, tt.alias, resp.GetShortlink().GetId())
+				}
+			}
+		})
+	}
+}
+
+func TestGetShortlink(t *testing.T) {
+	client := prepareClient(t)
+
+	created, err := client.CreateShortlink(authContext(dummyUserID), &pb.CreateShortlinkRequest{Url: "https://example.org"})
+	require.NoError(t, err)
+
+	_, err = client.GetShortlink(context.Background(), &pb.GetShortlinkRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	resp, err := client.GetShortlink(context.Background(), &pb.GetShortlinkRequest{Id: created.GetShortlink().GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", resp.GetShortlink().GetOriginalUrl())
+}
+
+func TestListUserShortlinks(t *testing.T) {
+	client := prepareClient(t)
+
+	for i := 1; i <= 3; i++ {
+		_, err := client.CreateShortlink(authContext(dummyUserID), &pb.CreateShortlinkRequest{Url: fmt.Sprintf("https://example.org/%d", i)})
+		require.NoError(t, err)
+	}
+
+	resp, err := client.ListUserShortlinks(authContext(dummyUserID), &pb.ListUserShortlinksRequest{})
+	require.NoError(t, err)
+	assert.Len(t, resp.GetShortlinks(), 3)
+	assert.Empty(t, resp.GetNext())
+
+	// Paged
+	var urls []string
+	req := &pb.ListUserShortlinksRequest{PageSize: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+
+		resp, err := client.ListUserShortlinks(authContext(dummyUserID), req)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(resp.GetShortlinks()), 2)
+		for _, link := range resp.GetShortlinks() {
+			urls = append(urls, link.GetOriginalUrl())
+		}
+		if resp.GetNext() == "" {
+			break
+		}
+		req.Cursor = resp.GetNext()
+	}
+	assert.ElementsMatch(t, []string{"https://example.org/1", "https://example.org/2", "https://example.org/3"}, urls)
+
+	_, err = client.ListUserShortlinks(authContext(dummyUserID), &pb.ListUserShortlinksRequest{Cursor: "bad cursor"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err = client.ListUserShortlinks(authContext("user2"), &pb.ListUserShortlinksRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetShortlinks())
+}
+
+func TestDeleteUserShortlinks(t *testing.T) {
+	client := prepareClient(t)
+
+	created, err := client.CreateShortlink(authContext(dummyUserID), &pb.CreateShortlinkRequest{Url: "https://example.org"})
+	require.NoError(t, err)
+
+	resp, err := client.DeleteUserShortlinks(authContext(dummyUserID), &pb.DeleteUserShortlinksRequest{
+		Ids: []string{created.GetShortlink().GetId(), "missing"},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, resp.GetJobId())
+
+	// Jobs of other users are not revealed
+	_, err = client.GetDeleteJob(authContext("user2"), &pb.GetDeleteJobRequest{Id: resp.GetJobId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	var job *pb.DeleteJob
+	require.Eventually(t, func() bool {
+		jobResp, err := client.GetDeleteJob(authContext(dummyUserID), &pb.GetDeleteJobRequest{Id: resp.GetJobId()})
+		require.NoError(t, err)
+		job = jobResp.GetJob()
+		return job.GetState() == entity.DeleteTaskDone
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, int32(2), job.GetRequested())
+	assert.Equal(t, int32(1), job.GetDeleted())
+	assert.Equal(t, []string{"missing"}, job.GetNotFound())
+	assert.NotNil(t, job.GetFinishedAt())
+}
+
+func prepareClient(t *testing.T) pb.ShortenerClient {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(interceptor.MetadataAuth(interceptor.MetadataAuthConfig{
+		Cipher: crypto.NewMock(),
+	}, log)))
+
+	repo := repository.NewInMemShortlinkRepo(nil)
+	clickRepo := repository.NewInMemClickRepo(100)
+	deleteQueue, err := repository.NewInMemDeleteQueue("", log)
+	require.NoError(t, err)
+	batchProc := batch.NewProcessor(context.Background(), config.Batch{}, repo, clickRepo, deleteQueue, log)
+	cfg := config.Shortener{
+		BaseURL:         "http://127.0.0.1",
+		DefaultLength:   5,
+		IDAlphabet:      "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+		IDMaxRetries:    3,
+		AliasAlphabet:   "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_",
+		AliasMinLength:  3,
+		AliasMaxLength:  64,
+		ReservedAliases: []string{"ping", "api"},
+	}
+	idLength, err := linkid.NewAdaptiveLength(cfg, repo.CountShortlinks, log)
+	require.NoError(t, err)
+	uc := usecase.NewShortener(cfg, linkid.NewRandom([]rune(cfg.IDAlphabet)), idLength, repo, clickRepo, batchProc, log)
+
+	NewShortenerController(server, uc, log)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return pb.NewShortenerClient(conn)
+}
+
+func authContext(userUID string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), interceptor.MetadataAuthName, userUID)
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/health"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+type HealthController struct {
+	checker *health.Checker
+
+	log *logger.Logger
+}
+
+func NewHealthController(router *gin.Engine, checker *health.Checker, log *logger.Logger) *HealthController {
+	c := &HealthController{
+		checker: checker,
+		log:     log,
+	}
+
+	router.GET("/healthz", c.healthz)
+	router.GET("/readyz", c.readyz)
+
+	return c
+}
+
+// healthz only tells that the process is alive and serving requests, dependencies are not checked
+func (ct *HealthController) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+func (ct *HealthController) readyz(c *gin.Context) {
+	ctx := c
+
+	report := ct.checker.Ready(ctx)
+	if report.Status != health.StatusOK {
+		ct.log.Warn(ctx).Msgf("not ready: %s", report.Status)
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/health"
+)
+
+func TestHealth(t *testing.T) {
+	okCheck := func(ctx context.Context) error { return nil }
+	failCheck := func(ctx context.Context) error { return errors.New("connection refused") }
+	slowCheck := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	type want struct {
+		code   int
+		status string
+		checks map[string]string
+	}
+	tests := []struct {
+		name         string
+		path         string
+		checks       map[string]health.Check
+		shuttingDown bool
+		want         want
+	}{
+		{
+			name:   "healthz ignores failing checks",
+			path:   "/healthz",
+			checks: map[string]health.Check{"repository": failCheck},
+			want: want{
+				code:   200,
+				status: health.StatusOK,
+			},
+		},
+		{
+			name:         "healthz while shutting down",
+			path:         "/healthz",
+			shuttingDown: true,
+			want: want{
+				code:   200,
+				status: health.StatusOK,
+			},
+		},
+		{
+			name:   "ready",
+			path:   "/readyz",
+			checks: map[string]health.Check{"repository": okCheck, "backup_storage": okCheck},
+			want: want{
+				code:   200,
+				status: health.StatusOK,
+				checks: map[string]string{"repository": health.StatusOK, "backup_storage": health.StatusOK},
+			},
+		},
+		{
+			name:   "failing check",
+			path:   "/readyz",
+			checks: map[string]health.Check{"repository": failCheck, "backup_storage": okCheck},
+			want: want{
+				code:   503,
+				status: health.StatusFail,
+				checks: map[string]string{"repository": health.StatusFail, "backup_storage": health.StatusOK},
+			},
+		},
+		{
+			name:   "check timeout",
+			path:   "/readyz",
+			checks: map[string]health.Check{"batch_processor": slowCheck},
+			want: want{
+				code:   503,
+				status: health.StatusFail,
+				checks: map[string]string{"batch_processor": health.StatusFail},
+			},
+		},
+		{
+			name:         "shutting down",
+			path:         "/readyz",
+			checks:       map[string]health.Check{"repository": okCheck},
+			shuttingDown: true,
+			want: want{
+				code:   503,
+				status: health.StatusShuttingDown,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := prepareRouter()
+			require.NoError(t, err)
+
+			checker := health.NewChecker(50 * time.Millisecond)
+			for name, check := range tt.checks {
+				checker.Register(name, check)
+			}
+			if tt.shuttingDown {
+				checker.Shutdown()
+			}
+			NewHealthController(srv, checker, log)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			body, resp, err := sendRequest(srv, req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, tt.want.code, resp.StatusCode)
+
+			var report health.Report
+			require.NoError(t, json.Unmarshal(body, &report))
+			assert.Equal(t, tt.want.status, report.Status)
+
+			checks := make(map[string]string)
+			for name, result := range report.Checks {
+				checks[name] = result.Status
+				if result.Status == health.StatusFail {
+					assert.NotEmpty(t, result.Error)
+				}
+			}
+			if tt.want.checks == nil {
+				assert.Empty(t, checks)
+			} else {
+				assert.Equal(t, tt.want.checks, checks)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/tracing"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func Tracing(log *logger.Logger) gin.HandlerFunc {
+	log.RegisterHook(func(ctx context.Context) (string, string) {
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			return "trace_id", traceID
+		}
+		return "", ""
+	})
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx, span := tracing.StartServer(c.Request.Context(), c.Request.Method+" "+route, propagation.HeaderCarrier(c.Request.Header),
+			semconv.HTTPMethod(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+			semconv.UserAgentOriginal(c.Request.UserAgent()),
+		)
+		defer span.End()
+
+		// Let the client find the trace of its request
+		tracing.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		code := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCode(code))
+		if code >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(code))
+		}
+	}
+}
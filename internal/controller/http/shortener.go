@@ -3,12 +3,16 @@ package http
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/usecase"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
@@ -20,7 +24,7 @@ type ShortenerController struct {
 	log *logger.Logger
 }
 
-func NewShortenerController(router *gin.Engine, shortener usecase.Shortener, log *logger.Logger) *ShortenerController {
+func NewShortenerController(router gin.IRouter, shortener usecase.Shortener, log *logger.Logger) *ShortenerController {
 	c := &ShortenerController{
 		shortener: shortener,
 		log:       log,
@@ -34,7 +38,13 @@ func NewShortenerController(router *gin.Engine, shortener usecase.Shortener, log
 	router.POST("/api/shorten", c.shortenLink)
 	router.POST("/api/shorten/batch", c.shortenLinksBatch)
 	router.GET("/api/user/urls", c.listShortlinks)
+	router.PATCH("/api/user/urls/:id", c.updateShortlink)
+	router.GET("/api/user/urls/:id/history", c.getShortlinkHistory)
 	router.DELETE("/api/user/urls", c.deleteShortlinks)
+	router.GET("/api/user/urls/trash", c.listTrash)
+	router.POST("/api/user/urls/restore", c.restoreShortlinks)
+	router.GET("/api/user/jobs/:id", c.getDeleteJob)
+	router.GET("/api/user/urls/:id/stats", c.getShortlinkStats)
 
 	return c
 }
@@ -67,7 +77,10 @@ func (ct *ShortenerController) createShortlink(c *gin.Context) {
 		return
 	}
 
-	link, err := ct.shortener.CreateShortlink(ctx, userUID, 0, string(body))
+	link, err := ct.shortener.CreateShortlink(ctx, usecase.CreateShortlinkIn{
+		UserUID: userUID,
+		URL:     string(body),
+	})
 
 	switch {
 	case err == nil:
@@ -82,7 +95,10 @@ func (ct *ShortenerController) createShortlink(c *gin.Context) {
 
 type (
 	shortenLinkRequest struct {
-		URL string `json:"url"`
+		URL       string     `json:"url"`
+		Alias     string     `json:"alias"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		TTL       int64      `json:"ttl"` // seconds
 	}
 	shortenLinkResponse struct {
 		Result string `json:"result"`
@@ -105,7 +121,13 @@ func (ct *ShortenerController) shortenLink(c *gin.Context) {
 		return
 	}
 
-	link, err := ct.shortener.CreateShortlink(ctx, userUID, 0, req.URL)
+	link, err := ct.shortener.CreateShortlink(ctx, usecase.CreateShortlinkIn{
+		UserUID:   userUID,
+		URL:       req.URL,
+		Alias:     req.Alias,
+		ExpiresAt: req.ExpiresAt,
+		TTL:       time.Duration(req.TTL) * time.Second,
+	})
 	var status int
 
 	switch {
@@ -126,8 +148,11 @@ func (ct *ShortenerController) shortenLink(c *gin.Context) {
 type (
 	shortenLinksBatchRequest     []shortenLinksBatchRequestLink
 	shortenLinksBatchRequestLink struct {
-		CorrelationID string `json:"correlation_id"`
-		OriginalURL   string `json:"original_url"`
+		CorrelationID string     `json:"correlation_id"`
+		OriginalURL   string     `json:"original_url"`
+		Alias         string     `json:"alias"`
+		ExpiresAt     *time.Time `json:"expires_at"`
+		TTL           int64      `json:"ttl"` // seconds
 	}
 	shortenLinksBatchResponse     []shortenLinksBatchResponseLink
 	shortenLinksBatchResponseLink struct {
@@ -156,6 +181,9 @@ func (ct *ShortenerController) shortenLinksBatch(c *gin.Context) {
 	for _, link := range req {
 		data = append(data, usecase.CreateShortlinksInLink{
 			URL:           link.OriginalURL,
+			Alias:         link.Alias,
+			ExpiresAt:     link.ExpiresAt,
+			TTL:           time.Duration(link.TTL) * time.Second,
 			CorrelationID: link.CorrelationID,
 		})
 	}
@@ -184,7 +212,7 @@ func (ct *ShortenerController) shortenLinksBatch(c *gin.Context) {
 
 type (
 	getShortlinkRequest struct {
-		LinkUID string `uri:"id" binding:"required" validate:"len=5"`
+		LinkUID string `uri:"id" binding:"required"`
 	}
 )
 
@@ -205,23 +233,55 @@ func (ct *ShortenerController) getShortlink(c *gin.Context) {
 		return
 	}
 	if link == nil {
+		metrics.Redirects.WithLabelValues(metrics.RedirectMiss).Inc()
 		c.Status(http.StatusNotFound)
 		return
 	}
-	if link.Deleted {
+	if link.Deleted || link.IsExpired(time.Now()) {
+		metrics.Redirects.WithLabelValues(metrics.RedirectGone).Inc()
 		c.Status(http.StatusGone)
 		return
 	}
+	metrics.Redirects.WithLabelValues(metrics.RedirectHit).Inc()
+
+	err = ct.shortener.RegisterClick(ctx, &entity.Click{
+		LinkUID:   link.UID,
+		Timestamp: time.Now(),
+		Referrer:  c.Request.Referer(),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("register click")
+	}
 
 	c.Header("Location", link.Long)
 	c.Status(http.StatusTemporaryRedirect)
 }
 
 type (
-	listShortlinksResponse     []listShortlinksResponseLink
+	listShortlinksQuery struct {
+		Limit        int        `form:"limit"`
+		Cursor       string     `form:"cursor"`
+		Sort         string     `form:"sort"`
+		Order        string     `form:"order" binding:"omitempty,oneof=asc desc"`
+		Domain       string     `form:"domain"`
+		Search       string     `form:"q"`
+		CreatedAfter *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	}
+	// listShortlinksResponse is all links of the user, returned when neither a limit nor a cursor is given
+	listShortlinksResponse []listShortlinksResponseLink
+	// listShortlinksPageResponse is a page of links, as ListUserShortlinksResponse of the gRPC API
+	listShortlinksPageResponse struct {
+		Links []listShortlinksResponseLink `json:"links"`
+		// Cursor of the next page, empty on the last one
+		Next string `json:"next,omitempty"`
+	}
 	listShortlinksResponseLink struct {
-		ShortURL    string `json:"short_url"`
-		OriginalURL string `json:"original_url"`
+		ShortURL    string    `json:"short_url"`
+		OriginalURL string    `json:"original_url"`
+		CreatedAt   time.Time `json:"created_at"`
+		UpdatedAt   time.Time `json:"updated_at"`
 	}
 )
 
@@ -234,30 +294,179 @@ func (ct *ShortenerController) listShortlinks(c *gin.Context) {
 		return
 	}
 
-	links, err := ct.shortener.ListUserShortlinks(ctx, userUID)
+	var query listShortlinksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ct.log.Error(ctx, err).Msg("parse query request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	listQuery := entity.ShortlinkQuery{
+		Sort:         query.Sort,
+		Desc:         query.Order == "desc",
+		Domain:       query.Domain,
+		Search:       query.Search,
+		CreatedAfter: query.CreatedAfter,
+		Limit:        query.Limit,
+	}
+	if query.Cursor != "" {
+		listQuery.After, err = entity.ParseShortlinkCursor(query.Cursor)
+		if err != nil {
+			ct.log.Error(ctx, err).Msg("parse cursor")
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	page, err := ct.shortener.ListUserShortlinks(ctx, userUID, listQuery)
 	if err != nil {
 		ct.log.Error(ctx, err).Msg("list user shortlinks")
 		c.String(ct.errorStatus(err), err.Error())
 		return
 	}
-	if len(links) == 0 {
+
+	result := make(listShortlinksResponse, 0, len(page.Links))
+	for _, link := range page.Links {
+		result = append(result, listShortlinksResponseLink{
+			ShortURL:    link.Short,
+			OriginalURL: link.Long,
+			CreatedAt:   link.CreatedAt,
+			UpdatedAt:   link.UpdatedAt,
+		})
+	}
+
+	// Paging is opt-in, clients that do not know about it still get a plain list of all links
+	if query.Limit > 0 || query.Cursor != "" {
+		pageResult := listShortlinksPageResponse{Links: result}
+		if page.Next != nil {
+			pageResult.Next = page.Next.String()
+		}
+		c.JSON(http.StatusOK, pageResult)
+		return
+	}
+
+	if len(result) == 0 {
 		c.Status(http.StatusNoContent)
 		return
 	}
+	c.JSON(http.StatusOK, result)
+}
 
-	var result listShortlinksResponse
+type (
+	updateShortlinkURI struct {
+		LinkUID string `uri:"id" binding:"required"`
+	}
+	updateShortlinkRequest struct {
+		URL string `json:"url" binding:"required"`
+	}
+	updateShortlinkResponse struct {
+		ID          string    `json:"id"`
+		ShortURL    string    `json:"short_url"`
+		OriginalURL string    `json:"original_url"`
+		UpdatedAt   time.Time `json:"updated_at"`
+	}
+)
 
-	for _, link := range links {
-		result = append(result, listShortlinksResponseLink{
-			ShortURL:    link.Short,
-			OriginalURL: link.Long,
+// updateShortlink retargets the link, on conflict the link that has the URL already is returned
+func (ct *ShortenerController) updateShortlink(c *gin.Context) {
+	ctx := c
+
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var uri updateShortlinkURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		ct.log.Error(ctx, err).Msg("parse URI request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req updateShortlinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ct.log.Error(ctx, err).Msg("parse JSON request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	link, err := ct.shortener.RetargetUserShortlink(ctx, userUID, uri.LinkUID, req.URL)
+
+	var status int
+	switch {
+	case err == nil:
+		status = http.StatusOK
+	case errors.Is(err, repository.ErrURLConflict):
+		status = http.StatusConflict
+	default:
+		ct.log.Error(ctx, err).Msg("retarget user shortlink")
+		c.String(ct.errorStatus(err), err.Error())
+		return
+	}
+
+	c.JSON(status, updateShortlinkResponse{
+		ID:          link.UID,
+		ShortURL:    link.Short,
+		OriginalURL: link.Long,
+		UpdatedAt:   link.UpdatedAt,
+	})
+}
+
+type (
+	getShortlinkHistoryRequest struct {
+		LinkUID string `uri:"id" binding:"required"`
+	}
+	getShortlinkHistoryResponse         []getShortlinkHistoryResponseRevision
+	getShortlinkHistoryResponseRevision struct {
+		OriginalURL string    `json:"original_url"`
+		ReplacedAt  time.Time `json:"replaced_at"`
+	}
+)
+
+func (ct *ShortenerController) getShortlinkHistory(c *gin.Context) {
+	ctx := c
+
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var req getShortlinkHistoryRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		ct.log.Error(ctx, err).Msg("parse URI request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	revisions, err := ct.shortener.GetUserShortlinkHistory(ctx, userUID, req.LinkUID)
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("get user shortlink history")
+		c.String(ct.errorStatus(err), err.Error())
+		return
+	}
+	if len(revisions) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	var result getShortlinkHistoryResponse
+
+	for _, revision := range revisions {
+		result = append(result, getShortlinkHistoryResponseRevision{
+			OriginalURL: revision.Long,
+			ReplacedAt:  revision.ReplacedAt,
 		})
 	}
 	c.JSON(http.StatusOK, result)
 }
 
 type (
-	deleteShortlinksRequest []string
+	deleteShortlinksRequest  []string
+	deleteShortlinksResponse struct {
+		JobID int64 `json:"job_id"`
+	}
 )
 
 func (ct *ShortenerController) deleteShortlinks(c *gin.Context) {
@@ -276,14 +485,261 @@ func (ct *ShortenerController) deleteShortlinks(c *gin.Context) {
 		return
 	}
 
-	err = ct.shortener.DeleteUserShortlinks(ctx, userUID, req)
+	job, err := ct.shortener.DeleteUserShortlinks(ctx, userUID, req)
 	if err != nil {
 		ct.log.Error(ctx, err).Msg("delete user shortlinks")
 		c.String(ct.errorStatus(err), err.Error())
 		return
 	}
 
-	c.Status(http.StatusAccepted)
+	c.Header("Location", fmt.Sprintf("/api/user/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, deleteShortlinksResponse{JobID: job.ID})
+}
+
+type (
+	listTrashResponse     []listTrashResponseLink
+	listTrashResponseLink struct {
+		ID          string    `json:"id"`
+		ShortURL    string    `json:"short_url"`
+		OriginalURL string    `json:"original_url"`
+		DeletedAt   time.Time `json:"deleted_at"`
+	}
+)
+
+func (ct *ShortenerController) listTrash(c *gin.Context) {
+	ctx := c
+
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	links, err := ct.shortener.ListUserTrash(ctx, userUID)
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("list user trash")
+		c.String(ct.errorStatus(err), err.Error())
+		return
+	}
+	if len(links) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	var result listTrashResponse
+
+	for _, link := range links {
+		item := listTrashResponseLink{
+			ID:          link.UID,
+			ShortURL:    link.Short,
+			OriginalURL: link.Long,
+		}
+		if link.DeletedAt != nil {
+			item.DeletedAt = *link.DeletedAt
+		}
+		result = append(result, item)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type (
+	restoreShortlinksRequest  []string
+	restoreShortlinksResponse struct {
+		Restored []string `json:"restored"`
+		NotFound []string `json:"not_found"`
+	}
+)
+
+func (ct *ShortenerController) restoreShortlinks(c *gin.Context) {
+	ctx := c
+
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var req restoreShortlinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ct.log.Error(ctx, err).Msg("parse JSON request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	restored, err := ct.shortener.RestoreUserShortlinks(ctx, userUID, req)
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("restore user shortlinks")
+		c.String(ct.errorStatus(err), err.Error())
+		return
+	}
+
+	result := restoreShortlinksResponse{
+		Restored: make([]string, 0, len(restored)),
+		NotFound: make([]string, 0),
+	}
+	// Keep the order of the request
+	for _, linkUID := range req {
+		if slices.Contains(restored, linkUID) {
+			result.Restored = append(result.Restored, linkUID)
+		} else {
+			result.NotFound = append(result.NotFound, linkUID)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type (
+	getDeleteJobRequest struct {
+		JobID int64 `uri:"id" binding:"required"`
+	}
+	getDeleteJobResponse struct {
+		ID        int64     `json:"id"`
+		State     string    `json:"state"`
+		Requested int       `json:"requested"`
+		Deleted   int       `json:"deleted"`
+		NotFound  []string  `json:"not_found"`
+		Attempts  int       `json:"attempts"`
+		LastError string    `json:"last_error,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+		// FinishedAt is set once the job is done or has failed
+		FinishedAt *time.Time `json:"finished_at,omitempty"`
+	}
+)
+
+func (ct *ShortenerController) getDeleteJob(c *gin.Context) {
+	ctx := c
+
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var req getDeleteJobRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		ct.log.Error(ctx, err).Msg("parse URI request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := ct.shortener.GetUserDeleteJob(ctx, userUID, req.JobID)
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("get user delete job")
+		c.String(ct.errorStatus(err), err.Error())
+		return
+	}
+
+	result := getDeleteJobResponse{
+		ID:        job.ID,
+		State:     job.State(),
+		Requested: len(job.LinkUIDs),
+		Deleted:   job.Deleted,
+		NotFound:  make([]string, 0, len(job.NotFound)),
+		Attempts:  job.Attempts,
+		LastError: job.LastError,
+		CreatedAt: job.CreatedAt,
+	}
+	if job.DoneAt != nil {
+		result.FinishedAt = job.DoneAt
+	} else {
+		result.FinishedAt = job.DeadAt
+	}
+	result.NotFound = append(result.NotFound, job.NotFound...)
+
+	c.JSON(http.StatusOK, result)
+}
+
+type (
+	getShortlinkStatsRequest struct {
+		LinkUID string `uri:"id" binding:"required"`
+	}
+	getShortlinkStatsQuery struct {
+		From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+		To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+		Bucket string    `form:"bucket"`
+		Top    int       `form:"top"`
+	}
+	getShortlinkStatsResponse struct {
+		ID             string                             `json:"id"`
+		TotalClicks    int64                              `json:"total_clicks"`
+		UniqueVisitors int64                              `json:"unique_visitors"`
+		Buckets        []getShortlinkStatsResponseBucket  `json:"buckets"`
+		TopReferrers   []getShortlinkStatsResponseCounter `json:"top_referrers"`
+		TopUserAgents  []getShortlinkStatsResponseCounter `json:"top_user_agents"`
+	}
+	getShortlinkStatsResponseBucket struct {
+		Start  time.Time `json:"start"`
+		Clicks int64     `json:"clicks"`
+	}
+	getShortlinkStatsResponseCounter struct {
+		Value  string `json:"value"`
+		Clicks int64  `json:"clicks"`
+	}
+)
+
+func (ct *ShortenerController) getShortlinkStats(c *gin.Context) {
+	ctx := c
+
+	userUID, err := ct.userUID(ctx)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var req getShortlinkStatsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		ct.log.Error(ctx, err).Msg("parse URI request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var query getShortlinkStatsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ct.log.Error(ctx, err).Msg("parse query request")
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := ct.shortener.GetUserShortlinkStats(ctx, userUID, req.LinkUID, entity.ClickStatsQuery{
+		From:   query.From,
+		To:     query.To,
+		Bucket: query.Bucket,
+		Top:    query.Top,
+	})
+	if err != nil {
+		ct.log.Error(ctx, err).Msg("get user shortlink stats")
+		c.String(ct.errorStatus(err), err.Error())
+		return
+	}
+
+	result := getShortlinkStatsResponse{
+		ID:             req.LinkUID,
+		TotalClicks:    stats.Total,
+		UniqueVisitors: stats.UniqueVisitors,
+		Buckets:        make([]getShortlinkStatsResponseBucket, 0, len(stats.Buckets)),
+		TopReferrers:   make([]getShortlinkStatsResponseCounter, 0, len(stats.TopReferrers)),
+		TopUserAgents:  make([]getShortlinkStatsResponseCounter, 0, len(stats.TopUserAgents)),
+	}
+	for _, bucket := range stats.Buckets {
+		result.Buckets = append(result.Buckets, getShortlinkStatsResponseBucket{
+			Start:  bucket.Start,
+			Clicks: bucket.Count,
+		})
+	}
+	for _, referrer := range stats.TopReferrers {
+		result.TopReferrers = append(result.TopReferrers, getShortlinkStatsResponseCounter{
+			Value:  referrer.Value,
+			Clicks: referrer.Count,
+		})
+	}
+	for _, userAgent := range stats.TopUserAgents {
+		result.TopUserAgents = append(result.TopUserAgents, getShortlinkStatsResponseCounter{
+			Value:  userAgent.Value,
+			Clicks: userAgent.Count,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
 func (ct *ShortenerController) userUID(c context.Context) (string, error) {
@@ -302,9 +758,29 @@ func (ct *ShortenerController) errorStatus(err error) int {
 	case errors.Is(err, usecase.ErrInvalidURL):
 		fallthrough
 	case errors.Is(err, usecase.ErrIncompleteURL):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidAlias):
+		fallthrough
+	case errors.Is(err, usecase.ErrReservedAlias):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidExpiry):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidStatsQuery):
+		fallthrough
+	case errors.Is(err, usecase.ErrInvalidListQuery):
+		fallthrough
+	case errors.Is(err, usecase.ErrNothingToDelete):
 		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrShortlinkNotFound):
+		fallthrough
+	case errors.Is(err, usecase.ErrDeleteJobNotFound):
+		return http.StatusNotFound
 	case errors.Is(err, usecase.ErrUIDConflict):
+		fallthrough
+	case errors.Is(err, usecase.ErrAliasConflict):
 		return http.StatusConflict
+	case errors.Is(err, usecase.ErrTooManyDeletes):
+		return http.StatusServiceUnavailable
 	case errors.Is(err, usecase.ErrDBUnavailable):
 		fallthrough
 	default:
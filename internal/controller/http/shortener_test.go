@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -19,6 +21,8 @@ import (
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/controller/http/middleware"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/crypto"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/linkid"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository/batch"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/usecase"
@@ -65,7 +69,7 @@ func TestCreateShortlink(t *testing.T) {
 			srv, err := prepareRouter()
 			require.NoError(t, err)
 
-			prepareController(srv, nil)
+			prepareController(srv, nil, nil)
 
 			reqBody := bytes.NewBufferString(tt.body)
 			req := httptest.NewRequest(http.MethodPost, "/", reqBody)
@@ -87,6 +91,121 @@ func TestCreateShortlink(t *testing.T) {
 	}
 }
 
+func TestCreateShortlinkRetriesTakenUID(t *testing.T) {
+	repo := repository.NewInMemShortlinkRepo(nil)
+	_, err := repo.SaveShortlink(context.Background(), &entity.Shortlink{UID: "00001", UserUID: "user2", Short: "http://127.0.0.1/00001", Long: "https://example.org"})
+	require.NoError(t, err)
+
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	prepareControllerWithIDs(srv, linkid.NewCounter([]rune("0123456789"), &linkid.InMemSequence{}), repo, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("https://example.com"))
+	addAuthCookie(req, dummyUserID)
+
+	body, resp, err := sendRequest(srv, req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.Equal(t, "http://127.0.0.1/00002", string(body))
+}
+
+// lookupCountingRepo counts lookups of links by UIDs
+type lookupCountingRepo struct {
+	repository.ShortlinkRepo
+	lookups int
+}
+
+func (r *lookupCountingRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([]*entity.Shortlink, error) {
+	r.lookups++
+	return r.ShortlinkRepo.FindShortlinks(ctx, linkUIDs)
+}
+
+func TestShortenLinksBatchRetriesTakenUID(t *testing.T) {
+	repo := &lookupCountingRepo{ShortlinkRepo: repository.NewInMemShortlinkRepo(nil)}
+	_, err := repo.SaveShortlink(context.Background(), &entity.Shortlink{UID: "00001", UserUID: "user2", Short: "http://127.0.0.1/00001", Long: "https://example.org"})
+	require.NoError(t, err)
+
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	prepareControllerWithIDs(srv, linkid.NewCounter([]rune("0123456789"), &linkid.InMemSequence{}), repo, nil)
+
+	reqBody := bytes.NewBufferString(`[
+		{"correlation_id": "1", "original_url": "https://example.org/1"},
+		{"correlation_id": "2", "original_url": "https://example.org/2"}
+	]`)
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	addAuthCookie(req, dummyUserID)
+
+	body, resp, err := sendRequest(srv, req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, 201, resp.StatusCode, string(body))
+
+	var respJSON shortenLinksBatchResponse
+	require.NoError(t, json.Unmarshal(body, &respJSON))
+
+	// The first attempt took 00001 and 00002, the whole batch is re-generated after the conflict
+	shorts := make(map[string]string, len(respJSON))
+	for _, link := range respJSON {
+		shorts[link.CorrelationID] = link.ShortURL
+	}
+	assert.Equal(t, map[string]string{
+		"1": "http://127.0.0.1/00003",
+		"2": "http://127.0.0.1/00004",
+	}, shorts)
+	assert.Zero(t, repo.lookups, "generated UIDs must not be looked up before saving")
+}
+
+// scriptedIDs generates the given UIDs in order
+type scriptedIDs struct {
+	uids []string
+}
+
+func (g *scriptedIDs) Generate(context.Context, int) (string, error) {
+	if len(g.uids) == 0 {
+		return "", fmt.Errorf("no more UIDs")
+	}
+	uid := g.uids[0]
+	g.uids = g.uids[1:]
+	return uid, nil
+}
+
+func TestShortenLinksBatchUniqueUIDs(t *testing.T) {
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	// The first two UIDs are taken by the alias and the previous link of the same batch
+	prepareControllerWithIDs(srv, &scriptedIDs{uids: []string{"sale1", "aaaaa", "aaaaa", "bbbbb"}}, nil, nil)
+
+	reqBody := bytes.NewBufferString(`[
+		{"correlation_id": "1", "original_url": "https://example.org/1", "alias": "sale1"},
+		{"correlation_id": "2", "original_url": "https://example.org/2"},
+		{"correlation_id": "3", "original_url": "https://example.org/3"}
+	]`)
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	addAuthCookie(req, dummyUserID)
+
+	body, resp, err := sendRequest(srv, req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, 201, resp.StatusCode, string(body))
+
+	var respJSON shortenLinksBatchResponse
+	require.NoError(t, json.Unmarshal(body, &respJSON))
+
+	shorts := make(map[string]string, len(respJSON))
+	for _, link := range respJSON {
+		shorts[link.CorrelationID] = link.ShortURL
+	}
+	assert.Equal(t, map[string]string{
+		"1": "http://127.0.0.1/sale1",
+		"2": "http://127.0.0.1/aaaaa",
+		"3": "http://127.0.0.1/bbbbb",
+	}, shorts)
+}
+
 func TestShortenLink(t *testing.T) {
 	type want struct {
 		code int
@@ -135,13 +254,84 @@ func TestShortenLink(t *testing.T) {
 				code: 201,
 			},
 		},
+		{
+			name: "alias with invalid characters",
+			req:  `{"url": "https://example.org", "alias": "spring sale!"}`,
+			want: want{
+				code: 400,
+				err:  usecase.ErrInvalidAlias.Error(),
+			},
+		},
+		{
+			name: "alias too short",
+			req:  `{"url": "https://example.org", "alias": "ss"}`,
+			want: want{
+				code: 400,
+				err:  usecase.ErrInvalidAlias.Error(),
+			},
+		},
+		{
+			name: "reserved alias",
+			req:  `{"url": "https://example.org", "alias": "Ping"}`,
+			want: want{
+				code: 400,
+				err:  usecase.ErrReservedAlias.Error(),
+			},
+		},
+		{
+			name: "alias taken",
+			req:  `{"url": "https://example.org", "alias": "taken"}`,
+			want: want{
+				code: 409,
+				err:  usecase.ErrAliasConflict.Error(),
+			},
+		},
+		{
+			name: "expiry in the past",
+			req:  `{"url": "https://example.org", "expires_at": "2020-01-01T00:00:00Z"}`,
+			want: want{
+				code: 400,
+				err:  usecase.ErrInvalidExpiry.Error(),
+			},
+		},
+		{
+			name: "both expiry and ttl",
+			req:  `{"url": "https://example.org", "expires_at": "2100-01-01T00:00:00Z", "ttl": 60}`,
+			want: want{
+				code: 400,
+				err:  usecase.ErrInvalidExpiry.Error(),
+			},
+		},
+		{
+			name: "ok with ttl",
+			req:  `{"url": "https://example.org", "ttl": 3600}`,
+			want: want{
+				code: 201,
+			},
+		},
+		{
+			name: "ok with alias",
+			req:  `{"url": "https://example.org", "alias": "spring-sale"}`,
+			want: want{
+				code: 201,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			srv, err := prepareRouter()
 			require.NoError(t, err)
 
-			prepareController(srv, nil)
+			repo := repository.NewInMemShortlinkRepo(nil)
+			_, err = repo.SaveShortlink(context.Background(), &entity.Shortlink{
+				UID:     "taken",
+				UserUID: "user2",
+				Short:   "http://127.0.0.1/taken",
+				Long:    "https://google.com",
+			})
+			require.NoError(t, err)
+
+			prepareController(srv, repo, nil)
 
 			reqBody := bytes.NewBufferString(tt.req)
 			req := httptest.NewRequest(http.MethodPost, "/api/shorten", reqBody)
@@ -173,6 +363,7 @@ func TestShortenLink(t *testing.T) {
 func TestGetShortlink(t *testing.T) {
 	ctx := context.Background()
 	repo := repository.NewInMemShortlinkRepo(nil)
+	expired := time.Now().Add(-time.Hour)
 
 	for _, link := range []entity.Shortlink{
 		{
@@ -187,7 +378,15 @@ func TestGetShortlink(t *testing.T) {
 			Short:   "http://127.0.0.1/link2",
 			Long:    "https://google.com",
 		},
+		{
+			UID:       "link4",
+			UserUID:   dummyUserID,
+			Short:     "http://127.0.0.1/link4",
+			Long:      "https://example.com",
+			ExpiresAt: &expired,
+		},
 	} {
+		link := link
 		_, err := repo.SaveShortlink(ctx, &link)
 		require.NoError(t, err)
 	}
@@ -195,6 +394,7 @@ func TestGetShortlink(t *testing.T) {
 	type want struct {
 		code     int
 		redirect string
+		result   string
 	}
 	tests := []struct {
 		name string
@@ -212,7 +412,16 @@ func TestGetShortlink(t *testing.T) {
 			name: "not found",
 			id:   "link3",
 			want: want{
-				code: 404,
+				code:   404,
+				result: metrics.RedirectMiss,
+			},
+		},
+		{
+			name: "expired",
+			id:   "link4",
+			want: want{
+				code:   410,
+				result: metrics.RedirectGone,
 			},
 		},
 		{
@@ -221,6 +430,7 @@ func TestGetShortlink(t *testing.T) {
 			want: want{
 				code:     307,
 				redirect: "https://google.com",
+				result:   metrics.RedirectHit,
 			},
 		},
 	}
@@ -229,17 +439,26 @@ func TestGetShortlink(t *testing.T) {
 			srv, err := prepareRouter()
 			require.NoError(t, err)
 
-			prepareController(srv, repo)
+			prepareController(srv, repo, nil)
 
 			req := httptest.NewRequest(http.MethodGet, "/"+tt.id, nil)
 			addAuthCookie(req, dummyUserID)
 
+			var before float64
+			if tt.want.result != "" {
+				before = testutil.ToFloat64(metrics.Redirects.WithLabelValues(tt.want.result))
+			}
+
 			_, resp, err := sendRequest(srv, req)
 			require.NoError(t, err)
 			require.NoError(t, resp.Body.Close())
 
 			assert.Equal(t, tt.want.code, resp.StatusCode)
 
+			if tt.want.result != "" {
+				assert.Equal(t, before+1, testutil.ToFloat64(metrics.Redirects.WithLabelValues(tt.want.result)))
+			}
+
 			if tt.want.code == 307 {
 				require.NotEmpty(t, resp.Header.Get("Location"))
 				assert.Equal(t, tt.want.redirect, resp.Header.Get("Location"))
@@ -248,15 +467,542 @@ func TestGetShortlink(t *testing.T) {
 	}
 }
 
-func prepareController(handler *gin.Engine, repo repository.ShortlinkRepo) {
+func TestGetShortlinkExpiredAfterCleanup(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemShortlinkRepo(nil)
+
+	recentlyExpired := time.Now().Add(-time.Minute)
+	longExpired := time.Now().Add(-2 * time.Hour)
+	for _, link := range []*entity.Shortlink{
+		{UID: "link1", UserUID: dummyUserID, Short: "http://127.0.0.1/link1", Long: "https://example.org", ExpiresAt: &recentlyExpired},
+		{UID: "link2", UserUID: dummyUserID, Short: "http://127.0.0.1/link2", Long: "https://google.com", ExpiresAt: &longExpired},
+	} {
+		_, err := repo.SaveShortlink(ctx, link)
+		require.NoError(t, err)
+	}
+
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	prepareControllerWithBatch(srv, linkid.NewRandom([]rune("0123456789abcdef")), config.Batch{
+		CleanupInterval:  10 * time.Millisecond,
+		ExpiredRetention: time.Hour,
+	}, repo, nil)
+
+	// Let a few cleanups run
+	require.Eventually(t, func() bool {
+		found, err := repo.FindShortlink(ctx, "", "link2")
+		return err == nil && found == nil
+	}, time.Second, 10*time.Millisecond)
+
+	for id, code := range map[string]int{"link1": 410, "link2": 404} {
+		req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		_, resp, err := sendRequest(srv, req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, code, resp.StatusCode, id)
+	}
+}
+
+func TestGetShortlinkStats(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemShortlinkRepo(nil)
+	clickRepo := repository.NewInMemClickRepo(100)
+
+	for _, link := range []entity.Shortlink{
+		{
+			UID:     "link1",
+			UserUID: dummyUserID,
+			Short:   "http://127.0.0.1/link1",
+			Long:    "https://example.org",
+		},
+		{
+			UID:     "link2",
+			UserUID: "user2",
+			Short:   "http://127.0.0.1/link2",
+			Long:    "https://google.com",
+		},
+	} {
+		link := link
+		_, err := repo.SaveShortlink(ctx, &link)
+		require.NoError(t, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	err := clickRepo.SaveClicks(ctx, []*entity.Click{
+		{LinkUID: "link1", Timestamp: now.Add(-2 * time.Hour), Referrer: "https://t.me", UserAgent: "curl", IP: "10.0.0.0"},
+		{LinkUID: "link1", Timestamp: now.Add(-2 * time.Hour), Referrer: "https://t.me", UserAgent: "curl", IP: "10.0.0.0"},
+		{LinkUID: "link1", Timestamp: now.Add(-1 * time.Hour), Referrer: "https://vk.com", UserAgent: "firefox", IP: "10.0.1.0"},
+		{LinkUID: "link2", Timestamp: now.Add(-1 * time.Hour), Referrer: "https://vk.com", UserAgent: "firefox", IP: "10.0.1.0"},
+	})
+	require.NoError(t, err)
+
+	type want struct {
+		code     int
+		total    int64
+		unique   int64
+		buckets  int
+		referrer string
+	}
+	tests := []struct {
+		name  string
+		id    string
+		query string
+		want  want
+	}{
+		{
+			name: "not found",
+			id:   "link3",
+			want: want{
+				code: 404,
+			},
+		},
+		{
+			name: "not owner",
+			id:   "link2",
+			want: want{
+				code: 404,
+			},
+		},
+		{
+			name:  "bad bucket",
+			id:    "link1",
+			query: "bucket=week",
+			want: want{
+				code: 400,
+			},
+		},
+		{
+			name:  "ok",
+			id:    "link1",
+			query: "bucket=hour&from=" + url.QueryEscape(now.Add(-3*time.Hour).Format(time.RFC3339)) + "&to=" + url.QueryEscape(now.Format(time.RFC3339)),
+			want: want{
+				code:     200,
+				total:    3,
+				unique:   2,
+				buckets:  3,
+				referrer: "https://t.me",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := prepareRouter()
+			require.NoError(t, err)
+
+			prepareController(srv, repo, clickRepo)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls/"+tt.id+"/stats?"+tt.query, nil)
+			addAuthCookie(req, dummyUserID)
+
+			body, resp, err := sendRequest(srv, req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, tt.want.code, resp.StatusCode)
+
+			if tt.want.code == 200 {
+				var respJSON getShortlinkStatsResponse
+				err := json.Unmarshal(body, &respJSON)
+				require.NoError(t, err, "response should resemble a getShortlinkStatsResponse")
+
+				assert.Equal(t, tt.want.total, respJSON.TotalClicks)
+				assert.Equal(t, tt.want.unique, respJSON.UniqueVisitors)
+				assert.Len(t, respJSON.Buckets, tt.want.buckets)
+				require.NotEmpty(t, respJSON.TopReferrers)
+				assert.Equal(t, tt.want.referrer, respJSON.TopReferrers[0].Value)
+			}
+		})
+	}
+}
+
+func TestListShortlinks(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemShortlinkRepo(nil)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		uid := fmt.Sprintf("link%d", i)
+		_, err := repo.SaveShortlink(ctx, &entity.Shortlink{
+			UID:       uid,
+			UserUID:   dummyUserID,
+			Short:     "http://127.0.0.1/" + uid,
+			Long:      fmt.Sprintf("https://example.org/%d", i),
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+	}
+
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	prepareController(srv, repo, nil)
+
+	list := func(target string) ([]byte, *http.Response) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		addAuthCookie(req, dummyUserID)
+
+		body, resp, err := sendRequest(srv, req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return body, resp
+	}
+
+	// Without a limit or a cursor all links are returned, as before paging
+	body, resp := list("/api/user/urls")
+	require.Equal(t, 200, resp.StatusCode)
+
+	var links listShortlinksResponse
+	require.NoError(t, json.Unmarshal(body, &links))
+	require.Len(t, links, 3)
+
+	// With a limit or a cursor a page is returned along with the cursor of the next one
+	body, resp = list("/api/user/urls?limit=2&order=desc")
+	require.Equal(t, 200, resp.StatusCode)
+
+	var page listShortlinksPageResponse
+	require.NoError(t, json.Unmarshal(body, &page))
+	require.Len(t, page.Links, 2)
+	assert.Equal(t, "https://example.org/2", page.Links[0].OriginalURL)
+	assert.Equal(t, start.Add(2*time.Minute), page.Links[0].CreatedAt)
+	require.NotEmpty(t, page.Next)
+
+	body, resp = list("/api/user/urls?limit=2&order=desc&cursor=" + url.QueryEscape(page.Next))
+	require.Equal(t, 200, resp.StatusCode)
+
+	page = listShortlinksPageResponse{}
+	require.NoError(t, json.Unmarshal(body, &page))
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, "https://example.org/0", page.Links[0].OriginalURL)
+	assert.Empty(t, page.Next)
+
+	// An empty page keeps the shape
+	body, resp = list("/api/user/urls?limit=2&domain=ya.ru")
+	require.Equal(t, 200, resp.StatusCode)
+	assert.JSONEq(t, `{"links":[]}`, string(body))
+
+	cursor := (&entity.ShortlinkCursor{Sort: entity.ShortlinkSortLong, UID: "link1"}).String()
+
+	tests := []struct {
+		name string
+		url  string
+		code int
+	}{
+		{
+			name: "bad cursor",
+			url:  "/api/user/urls?cursor=abc",
+			code: 400,
+		},
+		{
+			name: "cursor of another order",
+			url:  "/api/user/urls?cursor=" + cursor,
+			code: 400,
+		},
+		{
+			name: "bad sort",
+			url:  "/api/user/urls?sort=clicks",
+			code: 400,
+		},
+		{
+			name: "bad order",
+			url:  "/api/user/urls?order=up",
+			code: 400,
+		},
+		{
+			name: "nothing matches",
+			url:  "/api/user/urls?domain=ya.ru",
+			code: 204,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp := list(tt.url)
+			assert.Equal(t, tt.code, resp.StatusCode)
+		})
+	}
+}
+
+func TestDeleteJob(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemShortlinkRepo(nil)
+	_, err := repo.SaveShortlink(ctx, &entity.Shortlink{
+		UID:     "link1",
+		UserUID: dummyUserID,
+		Short:   "http://127.0.0.1/link1",
+		Long:    "https://example.org",
+	})
+	require.NoError(t, err)
+
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	prepareController(srv, repo, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/user/urls", bytes.NewBufferString(`["link1", "link2"]`))
+	addAuthCookie(req, dummyUserID)
+
+	body, resp, err := sendRequest(srv, req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, 202, resp.StatusCode)
+
+	var created deleteShortlinksResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+	require.NotZero(t, created.JobID)
+	jobURL := fmt.Sprintf("/api/user/jobs/%d", created.JobID)
+	assert.Equal(t, jobURL, resp.Header.Get("Location"))
+
+	var job getDeleteJobResponse
+	require.Eventually(t, func() bool {
+		req := httptest.NewRequest(http.MethodGet, jobURL, nil)
+		addAuthCookie(req, dummyUserID)
+
+		body, resp, err := sendRequest(srv, req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, 200, resp.StatusCode)
+		require.NoError(t, json.Unmarshal(body, &job))
+
+		return job.State == entity.DeleteTaskDone
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, 2, job.Requested)
+	assert.Equal(t, 1, job.Deleted)
+	assert.Equal(t, []string{"link2"}, job.NotFound)
+	assert.NotNil(t, job.FinishedAt)
+
+	tests := []struct {
+		name    string
+		url     string
+		userUID string
+		code    int
+	}{
+		{
+			name:    "bad id",
+			url:     "/api/user/jobs/abc",
+			userUID: dummyUserID,
+			code:    400,
+		},
+		{
+			name:    "not found",
+			url:     fmt.Sprintf("/api/user/jobs/%d", created.JobID+1),
+			userUID: dummyUserID,
+			code:    404,
+		},
+		{
+			name:    "not owner",
+			url:     jobURL,
+			userUID: "user2",
+			code:    404,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			addAuthCookie(req, tt.userUID)
+
+			_, resp, err := sendRequest(srv, req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, tt.code, resp.StatusCode)
+		})
+	}
+
+	t.Run("nothing to delete", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/user/urls", bytes.NewBufferString(`[]`))
+		addAuthCookie(req, dummyUserID)
+
+		body, resp, err := sendRequest(srv, req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, 400, resp.StatusCode)
+		assert.Equal(t, usecase.ErrNothingToDelete.Error(), string(body))
+	})
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemShortlinkRepo(nil)
+	_, err := repo.SaveShortlink(ctx, &entity.Shortlink{
+		UID:     "link1",
+		UserUID: dummyUserID,
+		Short:   "http://127.0.0.1/link1",
+		Long:    "https://example.org",
+	})
+	require.NoError(t, err)
+
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	prepareController(srv, repo, nil)
+
+	listTrash := func() ([]byte, int) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/urls/trash", nil)
+		addAuthCookie(req, dummyUserID)
+
+		body, resp, err := sendRequest(srv, req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return body, resp.StatusCode
+	}
+
+	_, code := listTrash()
+	assert.Equal(t, 204, code)
+
+	_, err = repo.DeleteShortlinks(ctx, dummyUserID, []string{"link1"})
+	require.NoError(t, err)
+
+	body, code := listTrash()
+	require.Equal(t, 200, code)
+
+	var trash listTrashResponse
+	require.NoError(t, json.Unmarshal(body, &trash))
+	require.Len(t, trash, 1)
+	assert.Equal(t, "link1", trash[0].ID)
+	assert.False(t, trash[0].DeletedAt.IsZero())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/urls/restore", bytes.NewBufferString(`["link2", "link1"]`))
+	addAuthCookie(req, dummyUserID)
+
+	body, resp, err := sendRequest(srv, req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, 200, resp.StatusCode)
+
+	var restored restoreShortlinksResponse
+	require.NoError(t, json.Unmarshal(body, &restored))
+	assert.Equal(t, []string{"link1"}, restored.Restored)
+	assert.Equal(t, []string{"link2"}, restored.NotFound)
+
+	_, code = listTrash()
+	assert.Equal(t, 204, code)
+}
+
+func TestUpdateShortlink(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemShortlinkRepo(nil)
+	for _, link := range []*entity.Shortlink{
+		{UID: "link1", UserUID: dummyUserID, Short: "http://127.0.0.1/link1", Long: "https://example.org"},
+		{UID: "link2", UserUID: "user2", Short: "http://127.0.0.1/link2", Long: "https://google.com"},
+	} {
+		_, err := repo.SaveShortlink(ctx, link)
+		require.NoError(t, err)
+	}
+
+	srv, err := prepareRouter()
+	require.NoError(t, err)
+	prepareController(srv, repo, nil)
+
+	tests := []struct {
+		name   string
+		linkID string
+		body   string
+		code   int
+		want   string
+	}{
+		{
+			name:   "retargeted",
+			linkID: "link1",
+			body:   `{"url": "https://example.net"}`,
+			code:   200,
+			want:   "https://example.net",
+		},
+		{
+			name:   "URL of another link",
+			linkID: "link1",
+			body:   `{"url": "https://google.com"}`,
+			code:   409,
+			want:   "https://google.com",
+		},
+		{
+			name:   "invalid URL",
+			linkID: "link1",
+			body:   `{"url": "not a url"}`,
+			code:   400,
+		},
+		{
+			name:   "missing URL",
+			linkID: "link1",
+			body:   `{}`,
+			code:   400,
+		},
+		{
+			name:   "not owned",
+			linkID: "link2",
+			body:   `{"url": "https://ya.ru"}`,
+			code:   404,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/user/urls/"+tt.linkID, bytes.NewBufferString(tt.body))
+			addAuthCookie(req, dummyUserID)
+
+			body, resp, err := sendRequest(srv, req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, tt.code, resp.StatusCode)
+
+			if tt.want != "" {
+				var updated updateShortlinkResponse
+				require.NoError(t, json.Unmarshal(body, &updated))
+				assert.Equal(t, tt.want, updated.OriginalURL)
+			}
+		})
+	}
+
+	t.Run("history", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/urls/link1/history", nil)
+		addAuthCookie(req, dummyUserID)
+
+		body, resp, err := sendRequest(srv, req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, 200, resp.StatusCode)
+
+		var history getShortlinkHistoryResponse
+		require.NoError(t, json.Unmarshal(body, &history))
+		require.Len(t, history, 1)
+		assert.Equal(t, "https://example.org", history[0].OriginalURL)
+
+		req = httptest.NewRequest(http.MethodGet, "/api/user/urls/link2/history", nil)
+		addAuthCookie(req, dummyUserID)
+
+		_, resp, err = sendRequest(srv, req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+}
+
+func prepareController(handler *gin.Engine, repo repository.ShortlinkRepo, clickRepo repository.ClickRepo) {
+	prepareControllerWithIDs(handler, linkid.NewRandom([]rune("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")), repo, clickRepo)
+}
+
+func prepareControllerWithIDs(handler *gin.Engine, idGenerator linkid.LinkIDGenerator, repo repository.ShortlinkRepo, clickRepo repository.ClickRepo) {
+	prepareControllerWithBatch(handler, idGenerator, config.Batch{}, repo, clickRepo)
+}
+
+func prepareControllerWithBatch(handler *gin.Engine, idGenerator linkid.LinkIDGenerator, batchCfg config.Batch, repo repository.ShortlinkRepo, clickRepo repository.ClickRepo) {
 	if repo == nil {
 		repo = repository.NewInMemShortlinkRepo(nil)
 	}
-	batchProc := batch.NewProcessor(context.Background(), repo, log)
-	uc := usecase.NewShortener(config.Shortener{
-		BaseURL:       "http://127.0.0.1",
-		DefaultLength: 5,
-	}, repo, batchProc, log)
+	if clickRepo == nil {
+		clickRepo = repository.NewInMemClickRepo(100)
+	}
+	// Without a journal path the queue cannot fail to open
+	deleteQueue, _ := repository.NewInMemDeleteQueue("", log)
+	batchProc := batch.NewProcessor(context.Background(), batchCfg, repo, clickRepo, deleteQueue, log)
+	cfg := config.Shortener{
+		BaseURL:         "http://127.0.0.1",
+		DefaultLength:   5,
+		IDAlphabet:      "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+		IDMaxRetries:    3,
+		AliasAlphabet:   "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_",
+		AliasMinLength:  3,
+		AliasMaxLength:  64,
+		ReservedAliases: []string{"ping", "api"},
+	}
+	// The alphabet is valid, so it cannot fail
+	idLength, _ := linkid.NewAdaptiveLength(cfg, repo.CountShortlinks, log)
+	uc := usecase.NewShortener(cfg, idGenerator, idLength, repo, clickRepo, batchProc, log)
 
 	NewShortenerController(handler, uc, log)
 }
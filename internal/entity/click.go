@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+type Click struct {
+	LinkUID   string    `json:"link_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Referrer  string    `json:"referrer"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+}
@@ -0,0 +1,49 @@
+package entity
+
+import "time"
+
+const (
+	ClickBucketHour = "hour"
+	ClickBucketDay  = "day"
+)
+
+type (
+	ClickStatsQuery struct {
+		From   time.Time
+		To     time.Time
+		Bucket string
+		Top    int
+	}
+	ClickStats struct {
+		Total          int64
+		UniqueVisitors int64
+		Buckets        []ClickBucket
+		TopReferrers   []ClickCounter
+		TopUserAgents  []ClickCounter
+	}
+	ClickBucket struct {
+		Start time.Time
+		Count int64
+	}
+	ClickCounter struct {
+		Value string
+		Count int64
+	}
+)
+
+// BucketStart returns the start of the bucket (in UTC) the given time falls into
+func (q ClickStatsQuery) BucketStart(t time.Time) time.Time {
+	t = t.UTC()
+	if q.Bucket == ClickBucketDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// NextBucket returns the start of the bucket following the given one
+func (q ClickStatsQuery) NextBucket(start time.Time) time.Time {
+	if q.Bucket == ClickBucketDay {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.Add(time.Hour)
+}
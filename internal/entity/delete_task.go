@@ -0,0 +1,50 @@
+package entity
+
+import "time"
+
+// States of a delete task, as seen by the user who requested it
+const (
+	DeleteTaskQueued  = "queued"
+	DeleteTaskRunning = "running"
+	DeleteTaskDone    = "done"
+	DeleteTaskFailed  = "failed"
+)
+
+// DeleteTask is a queued delete of user links, retried until it succeeds or is dead-lettered.
+// Once done, it is kept for a while with the results, so that the user can check on it
+type DeleteTask struct {
+	ID       int64    `json:"id"`
+	UserUID  string   `json:"user_id"`
+	LinkUIDs []string `json:"ids"`
+
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+
+	// Deleted is how many links were deleted, NotFound are UIDs that are unknown, not owned or already deleted
+	Deleted  int      `json:"deleted,omitempty"`
+	NotFound []string `json:"not_found,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+	DeadAt    *time.Time `json:"dead_at,omitempty"`
+}
+
+// State is running once the task has been attempted (and is being retried)
+func (t *DeleteTask) State() string {
+	switch {
+	case t.DoneAt != nil:
+		return DeleteTaskDone
+	case t.DeadAt != nil:
+		return DeleteTaskFailed
+	case t.Attempts > 0:
+		return DeleteTaskRunning
+	default:
+		return DeleteTaskQueued
+	}
+}
+
+// IsPending is true until the task is done or dead-lettered
+func (t *DeleteTask) IsPending() bool {
+	return t.DoneAt == nil && t.DeadAt == nil
+}
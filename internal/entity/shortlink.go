@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 type Shortlink struct {
 	UID     string `json:"id"`
 	UserUID string `json:"user_id"`
@@ -8,4 +10,18 @@ type Shortlink struct {
 	Deleted bool   `json:"deleted"`
 
 	CorrelationID string `json:"correlation_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is the last change of the link itself, e.g. moving it to trash and back
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// DeletedAt is when the link was moved to trash, it is purged after the trash retention period
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Revisions (oldest first) are only set in backups, so that storages without tables keep the history
+	Revisions []*ShortlinkRevision `json:"revisions,omitempty"`
+}
+
+func (l *Shortlink) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
 }
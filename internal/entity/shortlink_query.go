@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ShortlinkSortCreated = "created_at"
+	ShortlinkSortLong    = "original_url"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type (
+	// ShortlinkQuery selects a page of user links, ordered by Sort and then by UID, so that the order is total
+	ShortlinkQuery struct {
+		Sort string
+		Desc bool
+		// Domain matches the host of the long URL and its subdomains
+		Domain string
+		// Search is a case-insensitive substring of the long URL
+		Search       string
+		CreatedAfter *time.Time
+		// After is where the previous page ended
+		After *ShortlinkCursor
+		// Limit of zero returns all links
+		Limit int
+	}
+	// ShortlinkCursor is the sort key of the last link of a page
+	ShortlinkCursor struct {
+		Sort      string    `json:"s"`
+		Desc      bool      `json:"d,omitempty"`
+		CreatedAt time.Time `json:"c,omitempty"`
+		Long      string    `json:"l,omitempty"`
+		UID       string    `json:"u"`
+	}
+	ShortlinkPage struct {
+		Links []*Shortlink
+		// Next is nil on the last page
+		Next *ShortlinkCursor
+	}
+)
+
+// ParseShortlinkCursor decodes a cursor previously returned by ShortlinkCursor.String
+func ParseShortlinkCursor(s string) (*ShortlinkCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := new(ShortlinkCursor)
+	err = json.Unmarshal(data, cursor)
+	if err != nil || cursor.UID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// String is an opaque URL-safe encoding of the cursor
+func (c *ShortlinkCursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// CursorAt returns the cursor pointing right after the link
+func (q ShortlinkQuery) CursorAt(link *Shortlink) *ShortlinkCursor {
+	cursor := &ShortlinkCursor{Sort: q.Sort, Desc: q.Desc, UID: link.UID}
+	if q.Sort == ShortlinkSortLong {
+		cursor.Long = link.Long
+	} else {
+		cursor.CreatedAt = link.CreatedAt
+	}
+	return cursor
+}
+
+// Matches checks the filters and the cursor, but not the owner or the deleted flag
+func (q ShortlinkQuery) Matches(link *Shortlink) bool {
+	if q.Domain != "" && !MatchesDomain(link.Host(), q.Domain) {
+		return false
+	}
+	if q.Search != "" && !strings.Contains(strings.ToLower(link.Long), strings.ToLower(q.Search)) {
+		return false
+	}
+	if q.CreatedAfter != nil && !link.CreatedAt.After(*q.CreatedAfter) {
+		return false
+	}
+	if q.After != nil && !q.Less(q.After, q.CursorAt(link)) {
+		return false
+	}
+	return true
+}
+
+// Less reports whether a comes before b in the query order
+func (q ShortlinkQuery) Less(a, b *ShortlinkCursor) bool {
+	var cmp int
+	if q.Sort == ShortlinkSortLong {
+		cmp = strings.Compare(a.Long, b.Long)
+	} else {
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.UID, b.UID)
+	}
+	if q.Desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// Host of the long URL in lower case, empty if it cannot be parsed
+func (l *Shortlink) Host() string {
+	uri, err := neturl.Parse(l.Long)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(uri.Hostname())
+}
+
+// MatchesDomain is true if the host is the domain itself or its subdomain
+func MatchesDomain(host, domain string) bool {
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
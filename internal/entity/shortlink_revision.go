@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// ShortlinkRevision is a long URL the link pointed to before it was retargeted
+type ShortlinkRevision struct {
+	LinkUID    string    `json:"link_id"`
+	Long       string    `json:"long"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+type (
+	// Check reports whether a dependency is usable
+	Check func(ctx context.Context) error
+
+	// Checker runs the registered dependency checks for readiness,
+	// and stops being ready as soon as the shutdown begins
+	Checker struct {
+		timeout time.Duration
+		names   []string
+		checks  map[string]Check
+		mutex   sync.RWMutex
+
+		shuttingDown atomic.Bool
+	}
+	Report struct {
+		Status string                 `json:"status"`
+		Checks map[string]CheckResult `json:"checks,omitempty"`
+	}
+	CheckResult struct {
+		Status  string `json:"status"`
+		Error   string `json:"error,omitempty"`
+		Latency string `json:"latency"`
+	}
+)
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+func (h *Checker) Register(name string, check Check) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+// Shutdown marks the app as not ready, so that load balancers stop sending new traffic
+func (h *Checker) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *Checker) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Ready runs all checks concurrently, each one limited by the checker timeout
+func (h *Checker) Ready(ctx context.Context) Report {
+	if h.ShuttingDown() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	h.mutex.RLock()
+	names := h.names
+	checks := h.checks
+	h.mutex.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = h.run(ctx, check)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(names)),
+	}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (h *Checker) run(ctx context.Context, check Check) CheckResult {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{
+		Status:  StatusOK,
+		Latency: time.Since(start).Round(time.Microsecond).String(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
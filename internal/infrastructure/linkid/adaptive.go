@@ -0,0 +1,133 @@
+package linkid
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+// collisionRateWeight is how much the last generated ID counts in the collision rate (a moving average),
+// e.g. three collisions in a row take it from 0 to 0.14
+const collisionRateWeight = 0.05
+
+// AdaptiveLength is the default length of generated IDs. It grows as the keyspace of the current length fills up:
+// when too many of its IDs are taken (saturation), or when too many generated IDs turn out taken (collision rate)
+type AdaptiveLength struct {
+	mutex            sync.Mutex
+	length           int
+	maxLength        int
+	alphabetSize     float64
+	saturation       float64
+	maxSaturation    float64
+	collisionRate    float64
+	maxCollisionRate float64
+
+	count func(ctx context.Context) (int64, error)
+	log   *logger.Logger
+}
+
+// NewAdaptiveLength starts at the default length, count tells how many IDs are taken
+func NewAdaptiveLength(cfg config.Shortener, count func(ctx context.Context) (int64, error), log *logger.Logger) (*AdaptiveLength, error) {
+	alphabet, err := parseAlphabet(cfg.IDAlphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &AdaptiveLength{
+		length:           cfg.DefaultLength,
+		maxLength:        max(cfg.IDMaxLength, cfg.DefaultLength),
+		alphabetSize:     float64(len(alphabet)),
+		maxSaturation:    cfg.IDMaxSaturation,
+		maxCollisionRate: cfg.IDMaxCollisionRate,
+		count:            count,
+		log:              log,
+	}
+	l.report()
+
+	return l, nil
+}
+
+func (l *AdaptiveLength) Length() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.length
+}
+
+// Observe records whether a generated ID of the default length was already taken
+func (l *AdaptiveLength) Observe(ctx context.Context, collided bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	observed := 0.0
+	if collided {
+		observed = 1
+	}
+	l.collisionRate = l.collisionRate*(1-collisionRateWeight) + observed*collisionRateWeight
+
+	if l.maxCollisionRate > 0 && l.collisionRate > l.maxCollisionRate && l.length < l.maxLength {
+		l.log.Warn(ctx).Msgf("Link ID collision rate is %.2f, length %d -> %d", l.collisionRate, l.length, l.length+1)
+		l.grow()
+		l.collisionRate = 0
+	}
+	l.report()
+}
+
+// Refresh counts taken IDs, and grows the length until the saturation is below the maximum
+func (l *AdaptiveLength) Refresh(ctx context.Context) error {
+	count, err := l.count(ctx)
+	if err != nil {
+		return l.log.Wrap(err, "count links")
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.saturation = float64(count) / math.Pow(l.alphabetSize, float64(l.length))
+	for l.maxSaturation > 0 && l.saturation > l.maxSaturation && l.length < l.maxLength {
+		l.log.Warn(ctx).Msgf("Link ID keyspace saturation is %.4f, length %d -> %d", l.saturation, l.length, l.length+1)
+		l.grow()
+	}
+	l.report()
+
+	return nil
+}
+
+// Run refreshes the length every interval until the context is done
+func (l *AdaptiveLength) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := l.Refresh(ctx)
+			if err != nil {
+				l.log.Error(ctx, err).Msg("refresh link ID length")
+			}
+		}
+	}
+}
+
+// grow must be called with the mutex held, the saturation is recalculated for the new length
+func (l *AdaptiveLength) grow() {
+	l.length++
+	l.saturation /= l.alphabetSize
+}
+
+func (l *AdaptiveLength) report() {
+	metrics.LinkIDLength.Set(float64(l.length))
+	metrics.LinkIDKeyspaceSaturation.Set(l.saturation)
+	metrics.LinkIDCollisionRate.Set(l.collisionRate)
+}
@@ -0,0 +1,89 @@
+package linkid
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestAdaptiveLengthSaturation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		count int64
+		want  int
+	}{
+		{
+			name:  "below threshold",
+			count: 62 * 62 / 100,
+			want:  2,
+		},
+		{
+			name:  "one character more",
+			count: 62 * 62 / 2,
+			want:  3,
+		},
+		{
+			name:  "several characters more",
+			count: 62 * 62 * 62 * 62,
+			want:  5,
+		},
+		{
+			name:  "capped",
+			count: 62 * 62 * 62 * 62 * 62 * 62,
+			want:  6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count := func(ctx context.Context) (int64, error) { return tt.count, nil }
+			length, err := NewAdaptiveLength(config.Shortener{DefaultLength: 2, IDMaxLength: 6, IDMaxSaturation: 0.05, IDAlphabet: base62}, count, logger.NewMockLogger())
+			require.NoError(t, err)
+
+			require.NoError(t, length.Refresh(ctx))
+			assert.Equal(t, tt.want, length.Length())
+		})
+	}
+
+	t.Run("count fails", func(t *testing.T) {
+		count := func(ctx context.Context) (int64, error) { return 0, errors.New("db is down") }
+		length, err := NewAdaptiveLength(config.Shortener{DefaultLength: 2, IDMaxLength: 6, IDMaxSaturation: 0.05, IDAlphabet: base62}, count, logger.NewMockLogger())
+		require.NoError(t, err)
+
+		assert.Error(t, length.Refresh(ctx))
+		assert.Equal(t, 2, length.Length())
+	})
+}
+
+func TestAdaptiveLengthCollisions(t *testing.T) {
+	ctx := context.Background()
+	count := func(ctx context.Context) (int64, error) { return 0, nil }
+
+	length, err := NewAdaptiveLength(config.Shortener{DefaultLength: 5, IDMaxLength: 6, IDMaxCollisionRate: 0.1, IDAlphabet: base62}, count, logger.NewMockLogger())
+	require.NoError(t, err)
+
+	// Occasional collisions are fine
+	for i := 0; i < 100; i++ {
+		length.Observe(ctx, i%20 == 0)
+	}
+	assert.Equal(t, 5, length.Length())
+
+	for i := 0; i < 3; i++ {
+		length.Observe(ctx, true)
+	}
+	assert.Equal(t, 6, length.Length())
+
+	// Never beyond the maximum
+	for i := 0; i < 10; i++ {
+		length.Observe(ctx, true)
+	}
+	assert.Equal(t, 6, length.Length())
+}
@@ -0,0 +1,44 @@
+package linkid
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Counter IDs are the numbers of a sequence written in the base of the alphabet, so they never collide
+type Counter struct {
+	alphabet []rune
+	sequence Sequence
+}
+
+func NewCounter(alphabet []rune, sequence Sequence) *Counter {
+	return &Counter{
+		alphabet: alphabet,
+		sequence: sequence,
+	}
+}
+
+func (c *Counter) Generate(ctx context.Context, length int) (string, error) {
+	n, err := c.sequence.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+	return encode(n, c.alphabet, length), nil
+}
+
+// InMemSequence is not shared between instances or restarts, so it is best-effort: it starts after the given
+// number (e.g. the count of stored links), and numbers of links purged since then may be taken again.
+// Such IDs fail to save with ErrUIDConflict and are retried with the next number
+type InMemSequence struct {
+	last atomic.Uint64
+}
+
+func NewInMemSequence(start uint64) *InMemSequence {
+	seq := &InMemSequence{}
+	seq.last.Store(start)
+	return seq
+}
+
+func (s *InMemSequence) Next(ctx context.Context) (uint64, error) {
+	return s.last.Add(1), nil
+}
@@ -0,0 +1,52 @@
+package linkid
+
+import (
+	"context"
+	"slices"
+)
+
+// Hashids IDs are the numbers of a sequence, obfuscated the way the Hashids library does it:
+// the first character (the lottery) picks one of the salted shuffles of the alphabet, the rest is the number in it.
+// Consecutive numbers look unrelated, yet the IDs never collide
+type Hashids struct {
+	alphabet []rune
+	salt     []rune
+	sequence Sequence
+}
+
+func NewHashids(alphabet []rune, salt string, sequence Sequence) *Hashids {
+	return &Hashids{
+		alphabet: consistentShuffle(alphabet, []rune(salt)),
+		salt:     []rune(salt),
+		sequence: sequence,
+	}
+}
+
+func (h *Hashids) Generate(ctx context.Context, length int) (string, error) {
+	n, err := h.sequence.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	lottery := h.alphabet[n%uint64(len(h.alphabet))]
+	salt := append(append([]rune{lottery}, h.salt...), h.alphabet...)
+	alphabet := consistentShuffle(h.alphabet, salt[:len(h.alphabet)])
+
+	return string(lottery) + encode(n, alphabet, length-1), nil
+}
+
+// consistentShuffle is the Hashids shuffle, the same salt always gives the same order
+func consistentShuffle(alphabet, salt []rune) []rune {
+	result := slices.Clone(alphabet)
+	if len(salt) == 0 {
+		return result
+	}
+
+	for i, v, p := len(result)-1, 0, 0; i > 0; i-- {
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		result[i], result[j] = result[j], result[i]
+		v = (v + 1) % len(salt)
+	}
+	return result
+}
@@ -0,0 +1,15 @@
+package linkid
+
+import "context"
+
+type (
+	// LinkIDGenerator makes IDs for new links, it must be safe for concurrent use
+	LinkIDGenerator interface {
+		// Generate returns an ID of at least length characters, only random IDs are exactly that long
+		Generate(ctx context.Context, length int) (string, error)
+	}
+	// Sequence yields increasing numbers, never the same one twice
+	Sequence interface {
+		Next(ctx context.Context) (uint64, error)
+	}
+)
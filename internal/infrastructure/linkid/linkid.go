@@ -0,0 +1,70 @@
+package linkid
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+)
+
+var (
+	ErrUnknownStrategy = errors.New("unknown link ID strategy")
+	ErrInvalidAlphabet = errors.New("alphabet must have 2 to 256 unique characters")
+	ErrInvalidNode     = fmt.Errorf("snowflake node must be between 0 and %d", maxSnowflakeNode)
+)
+
+// New returns the generator of the configured strategy.
+// Counter and hashids IDs are numbers of the sequence, if it is nil, they are counted in memory from zero
+func New(cfg config.Shortener, sequence Sequence) (LinkIDGenerator, error) {
+	alphabet, err := parseAlphabet(cfg.IDAlphabet)
+	if err != nil {
+		return nil, err
+	}
+	if sequence == nil {
+		sequence = NewInMemSequence(0)
+	}
+
+	switch cfg.IDStrategy {
+	case config.IDStrategyRandom:
+		return NewRandom(alphabet), nil
+	case config.IDStrategyCounter:
+		return NewCounter(alphabet, sequence), nil
+	case config.IDStrategyHashids:
+		return NewHashids(alphabet, cfg.IDSalt, sequence), nil
+	case config.IDStrategySnowflake:
+		return NewSnowflake(alphabet, cfg.IDNode)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, cfg.IDStrategy)
+}
+
+func parseAlphabet(s string) ([]rune, error) {
+	alphabet := []rune(s)
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return nil, ErrInvalidAlphabet
+	}
+
+	sorted := slices.Clone(alphabet)
+	slices.Sort(sorted)
+	if len(slices.Compact(sorted)) != len(alphabet) {
+		return nil, ErrInvalidAlphabet
+	}
+	return alphabet, nil
+}
+
+// encode writes n in the base of the alphabet, padded with its first character up to length
+func encode(n uint64, alphabet []rune, length int) string {
+	base := uint64(len(alphabet))
+
+	var digits []rune
+	for n > 0 {
+		digits = append(digits, alphabet[n%base])
+		n /= base
+	}
+	for len(digits) < max(length, 1) {
+		digits = append(digits, alphabet[0])
+	}
+
+	slices.Reverse(digits)
+	return string(digits)
+}
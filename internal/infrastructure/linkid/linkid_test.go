@@ -0,0 +1,203 @@
+package linkid
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+)
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func TestGenerators(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		strategy string
+		exact    bool
+	}{
+		{strategy: config.IDStrategyRandom, exact: true},
+		{strategy: config.IDStrategyCounter},
+		{strategy: config.IDStrategyHashids},
+		{strategy: config.IDStrategySnowflake},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			generator, err := New(config.Shortener{IDStrategy: tt.strategy, IDAlphabet: base62, IDSalt: "salt", IDNode: 1}, nil)
+			require.NoError(t, err)
+
+			const workers, perWorker, length = 8, 1000, 8
+
+			var (
+				mutex sync.Mutex
+				wg    sync.WaitGroup
+			)
+			ids := make(map[string]struct{})
+
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < perWorker; j++ {
+						id, err := generator.Generate(ctx, length)
+						assert.NoError(t, err)
+
+						mutex.Lock()
+						ids[id] = struct{}{}
+						mutex.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Len(t, ids, workers*perWorker)
+			for id := range ids {
+				if tt.exact {
+					assert.Len(t, id, length)
+				} else {
+					assert.GreaterOrEqual(t, len(id), length)
+				}
+				assert.Empty(t, strings.Trim(id, base62), id)
+			}
+		})
+	}
+}
+
+func TestRandomUsesWholeAlphabet(t *testing.T) {
+	id, err := NewRandom([]rune(base62)).Generate(context.Background(), 10000)
+	require.NoError(t, err)
+
+	for _, c := range base62 {
+		assert.Contains(t, id, string(c))
+	}
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	generator := NewCounter([]rune(base62), &InMemSequence{})
+
+	var ids []string
+	for i := 0; i < 63; i++ {
+		id, err := generator.Generate(ctx, 2)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []string{"01", "02"}, ids[:2])
+	assert.Equal(t, []string{"0z", "10"}, ids[60:62])
+}
+
+func TestCounterContinuesAfterStart(t *testing.T) {
+	// Seeded with the count of stored links, the IDs stay as short as the default length
+	generator := NewCounter([]rune(base62), NewInMemSequence(61))
+
+	id, err := generator.Generate(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, "00010", id)
+}
+
+func TestNewUsesSequence(t *testing.T) {
+	generator, err := New(config.Shortener{IDStrategy: config.IDStrategyCounter, IDAlphabet: base62}, &InMemSequence{})
+	require.NoError(t, err)
+
+	id, err := generator.Generate(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, "001", id)
+}
+
+func TestHashids(t *testing.T) {
+	ctx := context.Background()
+
+	generate := func(salt string) []string {
+		generator := NewHashids([]rune(base62), salt, &InMemSequence{})
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := generator.Generate(ctx, 5)
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	ids := generate("salt")
+	assert.Equal(t, ids, generate("salt"))
+	assert.NotEqual(t, ids, generate("pepper"))
+	// Consecutive numbers do not share a prefix
+	assert.NotEqual(t, ids[0][:4], ids[1][:4])
+}
+
+func TestSnowflake(t *testing.T) {
+	ctx := context.Background()
+	now := snowflakeEpoch.Add(time.Hour)
+
+	generator, err := NewSnowflake([]rune(base62), 3)
+	require.NoError(t, err)
+	generator.now = func() time.Time { return now }
+
+	var last string
+	next := func() string {
+		id, err := generator.Generate(ctx, 0)
+		require.NoError(t, err)
+		// IDs of the same length compare as numbers
+		assert.Greater(t, id, last)
+		last = id
+		return id
+	}
+
+	next()
+	assert.Equal(t, uint64(0), generator.sequence)
+
+	// Sequence runs out within the millisecond
+	for i := 0; i < maxSnowflakeSequence+1; i++ {
+		next()
+	}
+	assert.Equal(t, now.Sub(snowflakeEpoch).Milliseconds()+1, generator.last)
+
+	// Clock goes back
+	now = now.Add(-time.Second)
+	next()
+	assert.Equal(t, uint64(1), generator.sequence)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Shortener
+		err  error
+	}{
+		{
+			name: "unknown strategy",
+			cfg:  config.Shortener{IDStrategy: "uuid", IDAlphabet: base62},
+			err:  ErrUnknownStrategy,
+		},
+		{
+			name: "duplicate characters",
+			cfg:  config.Shortener{IDStrategy: config.IDStrategyRandom, IDAlphabet: "abca"},
+			err:  ErrInvalidAlphabet,
+		},
+		{
+			name: "single character",
+			cfg:  config.Shortener{IDStrategy: config.IDStrategyRandom, IDAlphabet: "a"},
+			err:  ErrInvalidAlphabet,
+		},
+		{
+			name: "node out of range",
+			cfg:  config.Shortener{IDStrategy: config.IDStrategySnowflake, IDAlphabet: base62, IDNode: 1024},
+			err:  ErrInvalidNode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, nil)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
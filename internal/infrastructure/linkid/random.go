@@ -0,0 +1,43 @@
+package linkid
+
+import (
+	"context"
+	"crypto/rand"
+)
+
+// Random IDs are made of cryptographically random characters, collisions are possible
+type Random struct {
+	alphabet []rune
+	// Bytes from limit up are skipped, so that every character is equally likely
+	limit int
+}
+
+func NewRandom(alphabet []rune) *Random {
+	return &Random{
+		alphabet: alphabet,
+		limit:    256 - 256%len(alphabet),
+	}
+}
+
+func (r *Random) Generate(ctx context.Context, length int) (string, error) {
+	id := make([]rune, 0, length)
+	buf := make([]byte, length*2)
+
+	for len(id) < length {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= r.limit {
+				continue
+			}
+			id = append(id, r.alphabet[int(b)%len(r.alphabet)])
+			if len(id) == length {
+				break
+			}
+		}
+	}
+
+	return string(id), nil
+}
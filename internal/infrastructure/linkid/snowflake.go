@@ -0,0 +1,59 @@
+package linkid
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	maxSnowflakeNode      = 1<<snowflakeNodeBits - 1
+	maxSnowflakeSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake IDs are milliseconds since the epoch, the node and a per-millisecond sequence packed into a number.
+// Nodes never collide with each other, as long as each has its own number
+type Snowflake struct {
+	mutex    sync.Mutex
+	alphabet []rune
+	node     uint64
+	// Last used millisecond and sequence within it
+	last     int64
+	sequence uint64
+	now      func() time.Time
+}
+
+func NewSnowflake(alphabet []rune, node int64) (*Snowflake, error) {
+	if node < 0 || node > maxSnowflakeNode {
+		return nil, ErrInvalidNode
+	}
+	return &Snowflake{
+		alphabet: alphabet,
+		node:     uint64(node),
+		now:      time.Now,
+	}, nil
+}
+
+func (s *Snowflake) Generate(ctx context.Context, length int) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// If the clock goes back, or the sequence runs out, the last millisecond is reused or borrowed from the future
+	ms := max(s.now().Sub(snowflakeEpoch).Milliseconds(), s.last)
+	if ms == s.last {
+		s.sequence = (s.sequence + 1) & maxSnowflakeSequence
+		if s.sequence == 0 {
+			ms++
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = ms
+
+	id := uint64(ms)<<(snowflakeNodeBits+snowflakeSequenceBits) | s.node<<snowflakeSequenceBits | s.sequence
+	return encode(id, s.alphabet, length), nil
+}
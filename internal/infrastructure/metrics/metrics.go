@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortener"
+
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+
+	RedirectHit  = "hit"
+	RedirectMiss = "miss"
+	RedirectGone = "gone"
+
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+
+	BufferDeleteShortlinks = "delete_shortlinks"
+	BufferSaveClicks       = "save_clicks"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route and status code",
+	}, []string{"method", "route", "code"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and status code",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	RepoOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repo",
+		Name:      "operation_duration_seconds",
+		Help:      "Repository operation latency",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+	RepoOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "repo",
+		Name:      "operation_errors_total",
+		Help:      "Repository operations that returned an error",
+	}, []string{"operation"})
+
+	BatchBufferSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "batch",
+		Name:      "buffer_size",
+		Help:      "Items waiting in the batch processor buffers",
+	}, []string{"buffer"})
+	BatchFlushes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "batch",
+		Name:      "flushes_total",
+		Help:      "Batch processor buffer flushes by result",
+	}, []string{"buffer", "result"})
+	BatchDroppedClicks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "batch",
+		Name:      "dropped_clicks_total",
+		Help:      "Clicks dropped because the buffer was full",
+	})
+	BatchDeleteRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "batch",
+		Name:      "delete_retries_total",
+		Help:      "Failed delete tasks scheduled for another attempt",
+	})
+	BatchDeleteDeadLetters = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "batch",
+		Name:      "delete_dead_letters_total",
+		Help:      "Delete tasks given up on after running out of attempts",
+	})
+
+	UIDGenerationRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uid_generation_retries_total",
+		Help:      "Generated link UIDs that were already taken and had to be re-generated",
+	})
+	UIDGenerationFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uid_generation_failures_total",
+		Help:      "Shortlinks not created because no free link UID was found",
+	})
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Links looked up in the cache by result (hit or miss)",
+	}, []string{"result"})
+	CacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "entries",
+		Help:      "Links (and unknown UIDs) in the cache",
+	})
+
+	LinkIDLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "link_id_length",
+		Help:      "Default length of generated link UIDs",
+	})
+	LinkIDKeyspaceSaturation = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "link_id_keyspace_saturation",
+		Help:      "Share of the link UIDs of the default length that are taken",
+	})
+	LinkIDCollisionRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "link_id_collision_rate",
+		Help:      "Moving average of generated link UIDs that were already taken",
+	})
+
+	Redirects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Redirect requests by result (hit, miss, gone)",
+	}, []string{"result"})
+)
+
+// Handler exposes all registered metrics in the Prometheus format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
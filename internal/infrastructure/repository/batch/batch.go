@@ -2,65 +2,477 @@ package batch
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
 )
 
+const (
+	// A loop is reported as stalled after missing a few ticks, but never sooner than minStallTimeout
+	stallTicks      = 3
+	minStallTimeout = 10 * time.Second
+
+	clicksFlushInterval = time.Second
+)
+
+var (
+	ErrProcessorStopped = errors.New("batch processor is stopped")
+	ErrDeleteQueueFull  = errors.New("delete queue is full")
+)
+
 type (
 	Processor struct {
-		repo repository.ShortlinkRepo
-		log  *logger.Logger
+		cfg         config.Batch
+		repo        repository.ShortlinkRepo
+		clickRepo   repository.ClickRepo
+		deleteQueue repository.DeleteQueueRepo
+		log         *logger.Logger
 
-		deleteShortlinksChan chan shortlinksBatch
-	}
-	shortlinksBatch struct {
-		UserUID  string
-		LinkUIDs []string
+		// pendingDeletes is tracked locally, so that enqueueing does not need to count the queue
+		pendingDeletes atomic.Int64
+		saveClicksChan chan *entity.Click
+
+		// Unix nanos of the last tick of each loop, 0 once the loop has exited
+		deleteHeartbeat atomic.Int64
+		clicksHeartbeat atomic.Int64
+
+		// stopping is closed by Stop, stopCtx is set right before and limits the final flushes
+		stopped  atomic.Bool
+		stopping chan struct{}
+		stopCtx  context.Context
+		stopOnce sync.Once
+		wg       sync.WaitGroup
 	}
 )
 
-func NewProcessor(ctx context.Context, repo repository.ShortlinkRepo, log *logger.Logger) *Processor {
+func NewProcessor(ctx context.Context, cfg config.Batch, repo repository.ShortlinkRepo, clickRepo repository.ClickRepo, deleteQueue repository.DeleteQueueRepo, log *logger.Logger) *Processor {
 	p := &Processor{
-		repo: repo,
-		log:  log,
+		cfg:         cfg,
+		repo:        repo,
+		clickRepo:   clickRepo,
+		deleteQueue: deleteQueue,
+		log:         log,
+
+		saveClicksChan: make(chan *entity.Click, cfg.ClicksBufferSize),
+		stopping:       make(chan struct{}),
+	}
+	p.deleteHeartbeat.Store(time.Now().UnixNano())
+	p.clicksHeartbeat.Store(time.Now().UnixNano())
+
+	// Tasks left from the previous run are picked up by the first flush
+	pending, err := deleteQueue.CountPendingDeleteTasks(ctx)
+	if err != nil {
+		log.Error(ctx, err).Msg("count pending delete tasks")
+	}
+	p.pendingDeletes.Store(pending)
+	p.addPendingDeletes(0)
 
-		deleteShortlinksChan: make(chan shortlinksBatch),
+	p.wg.Add(2)
+	go p.processDeleteQueue(ctx)
+	go p.bufferClicksForSave(ctx)
+	if cfg.CleanupInterval > 0 {
+		p.wg.Add(1)
+		go p.cleanup(ctx)
 	}
-	go p.bufferShortlinksForDelete(ctx)
 
 	return p
 }
 
-func (p *Processor) BatchDeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) {
-	p.deleteShortlinksChan <- shortlinksBatch{UserUID: userUID, LinkUIDs: linkUIDs}
+// BatchDeleteShortlinks stores a delete task, links are deleted on the next flush.
+// Once it returns, the delete survives restarts and is retried until it succeeds or is dead-lettered
+func (p *Processor) BatchDeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) (*entity.DeleteTask, error) {
+	if p.stopped.Load() {
+		return nil, ErrProcessorStopped
+	}
+	if p.cfg.DeleteQueueDepth > 0 && p.pendingDeletes.Load() >= int64(p.cfg.DeleteQueueDepth) {
+		return nil, ErrDeleteQueueFull
+	}
+
+	now := time.Now().UTC()
+	task := &entity.DeleteTask{
+		UserUID:       userUID,
+		LinkUIDs:      linkUIDs,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	err := p.deleteQueue.EnqueueDeleteTask(ctx, task)
+	if err != nil {
+		return nil, p.log.Wrap(err, "enqueue delete task")
+	}
+
+	p.addPendingDeletes(1)
+	return task, nil
 }
 
-func (p *Processor) bufferShortlinksForDelete(ctx context.Context) {
-	buffer := make(map[string][]string)
-	ticker := time.NewTicker(time.Second)
+// GetDeleteTask returns nil if the task is unknown or has been purged after DeleteJobRetention
+func (p *Processor) GetDeleteTask(ctx context.Context, taskID int64) (*entity.DeleteTask, error) {
+	task, err := p.deleteQueue.GetDeleteTask(ctx, taskID)
+	if err != nil {
+		return nil, p.log.Wrap(err, "get delete task")
+	}
+	return task, nil
+}
+
+// BatchSaveClick never blocks the caller: if the buffer is full, the click is dropped
+func (p *Processor) BatchSaveClick(ctx context.Context, click *entity.Click) {
+	select {
+	case <-p.stopping:
+		p.log.Warn(ctx).Msgf("batch processor is stopped, dropping click for %s", click.LinkUID)
+		return
+	default:
+	}
+
+	select {
+	case p.saveClicksChan <- click:
+	default:
+		metrics.BatchDroppedClicks.Inc()
+		p.log.Warn(ctx).Msgf("clicks buffer is full, dropping click for %s", click.LinkUID)
+	}
+}
+
+// Stop runs the due deletes, saves the buffered clicks and waits for both loops to exit, unless ctx is done first
+func (p *Processor) Stop(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		p.stopped.Store(true)
+		p.stopCtx = ctx
+		close(p.stopping)
+	})
+
+	stopped := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ping reports whether both buffer loops are still running and ticking
+func (p *Processor) Ping(ctx context.Context) error {
+	if err := checkHeartbeat(&p.deleteHeartbeat, p.deleteFlushInterval()); err != nil {
+		return fmt.Errorf("%s loop: %w", metrics.BufferDeleteShortlinks, err)
+	}
+	if err := checkHeartbeat(&p.clicksHeartbeat, clicksFlushInterval); err != nil {
+		return fmt.Errorf("%s loop: %w", metrics.BufferSaveClicks, err)
+	}
+	return nil
+}
+
+func checkHeartbeat(heartbeat *atomic.Int64, interval time.Duration) error {
+	last := heartbeat.Load()
+	if last == 0 {
+		return ErrProcessorStopped
+	}
+	stallTimeout := max(stallTicks*interval, minStallTimeout)
+	if since := time.Since(time.Unix(0, last)); since > stallTimeout {
+		return fmt.Errorf("no tick for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+func (p *Processor) processDeleteQueue(ctx context.Context) {
+	defer p.wg.Done()
+	defer p.deleteHeartbeat.Store(0)
+
+	ticker := time.NewTicker(p.deleteFlushInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stopping:
+			p.flushDeletes(p.stopCtx)
+			return
+		case <-ticker.C:
+			p.deleteHeartbeat.Store(time.Now().UnixNano())
+			p.flushDeletes(ctx)
+		}
+	}
+}
+
+func (p *Processor) deleteFlushInterval() time.Duration {
+	if p.cfg.DeleteFlushInterval <= 0 {
+		return time.Second
+	}
+	return p.cfg.DeleteFlushInterval
+}
+
+// cleanup removes expired shortlinks, trash and old delete tasks on its own (usually much slower) ticker.
+// It is not watched by Ping, as a cleanup of a big table may take longer than any stall timeout
+func (p *Processor) cleanup(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.CleanupInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case batch := <-p.deleteShortlinksChan:
-			if _, ok := buffer[batch.UserUID]; !ok {
-				buffer[batch.UserUID] = batch.LinkUIDs
+		case <-p.stopping:
+			return
+		case now := <-ticker.C:
+			p.purgeExpired(ctx, now)
+			p.purgeTrash(ctx, now)
+			p.purgeDeleteTasks(ctx, now)
+		}
+	}
+}
+
+// flushDeletes runs due tasks batch by batch, until there are none left or something fails
+func (p *Processor) flushDeletes(ctx context.Context) {
+	batchSize := p.cfg.DeleteBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	for ctx.Err() == nil {
+		tasks, err := p.deleteQueue.GetDueDeleteTasks(ctx, time.Now().UTC(), batchSize)
+		if err != nil {
+			p.log.Error(ctx, err).Msg("get due delete tasks")
+			return
+		}
+		if len(tasks) == 0 {
+			return
+		}
+
+		// Failed tasks may still be due (e.g. the queue itself is unavailable), leave them until the next tick
+		ok := p.runDeleteTasks(ctx, tasks)
+		// A long flush that keeps going through batches is not stalled
+		p.deleteHeartbeat.Store(time.Now().UnixNano())
+		if !ok || len(tasks) < batchSize {
+			return
+		}
+	}
+}
+
+// runDeleteTasks deletes links of every user with a single query, returns false if anything failed
+func (p *Processor) runDeleteTasks(ctx context.Context, tasks []*entity.DeleteTask) bool {
+	var users []string
+	byUser := make(map[string][]*entity.DeleteTask)
+	for _, task := range tasks {
+		if _, ok := byUser[task.UserUID]; !ok {
+			users = append(users, task.UserUID)
+		}
+		byUser[task.UserUID] = append(byUser[task.UserUID], task)
+	}
+
+	ok := true
+	for _, userUID := range users {
+		userTasks := byUser[userUID]
+
+		var linkUIDs []string
+		for _, task := range userTasks {
+			linkUIDs = append(linkUIDs, task.LinkUIDs...)
+		}
+
+		deleted, err := p.repo.DeleteShortlinks(ctx, userUID, linkUIDs)
+		metrics.BatchFlushes.WithLabelValues(metrics.BufferDeleteShortlinks, metrics.Result(err)).Inc()
+		if err != nil {
+			p.log.Error(ctx, err).Msgf("delete %d shortlinks for user %s", len(linkUIDs), userUID)
+			for _, task := range userTasks {
+				p.retryDeleteTask(ctx, task, err)
+			}
+			ok = false
+			continue
+		}
+
+		completeDeleteTasks(userTasks, deleted)
+
+		// Deletes are idempotent, so if this fails the tasks are simply run again
+		err = p.deleteQueue.CompleteDeleteTasks(ctx, userTasks)
+		if err != nil {
+			p.log.Error(ctx, err).Msgf("complete %d delete tasks", len(userTasks))
+			ok = false
+			continue
+		}
+		p.addPendingDeletes(-int64(len(userTasks)))
+		p.log.Info(ctx).Msgf("delete %d of %d shortlinks for user %s", len(deleted), len(linkUIDs), userUID)
+	}
+	return ok
+}
+
+// completeDeleteTasks marks the tasks done and splits their links into deleted and not found ones.
+// A link deleted by the query is credited to the first task that asked for it
+func completeDeleteTasks(tasks []*entity.DeleteTask, deleted []string) {
+	uncredited := make(map[string]bool, len(deleted))
+	for _, linkUID := range deleted {
+		uncredited[linkUID] = true
+	}
+
+	now := time.Now().UTC()
+	for _, task := range tasks {
+		task.Attempts++
+		task.DoneAt = &now
+		task.Deleted = 0
+		task.NotFound = nil
+
+		seen := make(map[string]bool, len(task.LinkUIDs))
+		for _, linkUID := range task.LinkUIDs {
+			if seen[linkUID] {
+				continue
+			}
+			seen[linkUID] = true
+
+			if uncredited[linkUID] {
+				task.Deleted++
+				delete(uncredited, linkUID)
 			} else {
-				buffer[batch.UserUID] = append(buffer[batch.UserUID], batch.LinkUIDs...)
+				task.NotFound = append(task.NotFound, linkUID)
 			}
-		case <-ticker.C:
-			for userUID, linksUIDs := range buffer {
-				err := p.repo.DeleteShortlinks(ctx, userUID, linksUIDs)
-				if err != nil {
-					p.log.Error(ctx, err).Msg("delete shortlinks")
-				} else {
-					p.log.Info(ctx).Msgf("delete %d shortlinks for user %s", len(linksUIDs), userUID)
-				}
-				delete(buffer, userUID)
+		}
+	}
+}
+
+// purgeExpired hard-deletes links that expired longer than ExpiredRetention ago, until then they answer 410 Gone
+func (p *Processor) purgeExpired(ctx context.Context, now time.Time) {
+	if p.cfg.ExpiredRetention <= 0 {
+		return
+	}
+	deleted, err := p.repo.DeleteExpiredShortlinks(ctx, now.Add(-p.cfg.ExpiredRetention))
+	if err != nil {
+		p.log.Error(ctx, err).Msg("delete expired shortlinks")
+	} else if deleted > 0 {
+		p.log.Info(ctx).Msgf("delete %d expired shortlinks", deleted)
+	}
+}
+
+// purgeTrash hard-deletes links that have been in trash for longer than TrashRetention
+func (p *Processor) purgeTrash(ctx context.Context, now time.Time) {
+	if p.cfg.TrashRetention <= 0 {
+		return
+	}
+	purged, err := p.repo.PurgeDeletedShortlinks(ctx, now.Add(-p.cfg.TrashRetention))
+	if err != nil {
+		p.log.Error(ctx, err).Msg("purge deleted shortlinks")
+	} else if purged > 0 {
+		p.log.Info(ctx).Msgf("purge %d deleted shortlinks", purged)
+	}
+}
+
+// purgeDeleteTasks removes done tasks, once their status is not needed anymore
+func (p *Processor) purgeDeleteTasks(ctx context.Context, now time.Time) {
+	if p.cfg.DeleteJobRetention <= 0 {
+		return
+	}
+	purged, err := p.deleteQueue.PurgeDeleteTasks(ctx, now.Add(-p.cfg.DeleteJobRetention))
+	if err != nil {
+		p.log.Error(ctx, err).Msg("purge delete tasks")
+	} else if purged > 0 {
+		p.log.Info(ctx).Msgf("purge %d done delete tasks", purged)
+	}
+}
+
+// retryDeleteTask schedules the next attempt with exponential backoff, or dead-letters the task
+// once it has run out of attempts
+func (p *Processor) retryDeleteTask(ctx context.Context, task *entity.DeleteTask, cause error) {
+	task.Attempts++
+	task.LastError = cause.Error()
+
+	if p.cfg.DeleteMaxAttempts > 0 && task.Attempts >= p.cfg.DeleteMaxAttempts {
+		now := time.Now().UTC()
+		task.DeadAt = &now
+
+		err := p.deleteQueue.DeadLetterDeleteTask(ctx, task)
+		if err != nil {
+			p.log.Error(ctx, err).Msgf("dead-letter delete task %d", task.ID)
+			return
+		}
+		metrics.BatchDeleteDeadLetters.Inc()
+		p.addPendingDeletes(-1)
+		p.log.Error(ctx, cause).Msgf("delete task %d of user %s dead-lettered after %d attempts", task.ID, task.UserUID, task.Attempts)
+		return
+	}
+
+	task.NextAttemptAt = time.Now().UTC().Add(p.retryBackoff(task.Attempts))
+
+	err := p.deleteQueue.RetryDeleteTask(ctx, task)
+	if err != nil {
+		p.log.Error(ctx, err).Msgf("reschedule delete task %d", task.ID)
+		return
+	}
+	metrics.BatchDeleteRetries.Inc()
+	p.log.Warn(ctx).Msgf("delete task %d of user %s failed %d times, retrying at %s",
+		task.ID, task.UserUID, task.Attempts, task.NextAttemptAt.Format(time.RFC3339))
+}
+
+// retryBackoff doubles the base backoff on every attempt, up to the max one
+func (p *Processor) retryBackoff(attempts int) time.Duration {
+	backoff := p.cfg.DeleteRetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for i := 1; i < attempts; i++ {
+		if p.cfg.DeleteMaxBackoff > 0 && backoff >= p.cfg.DeleteMaxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	if p.cfg.DeleteMaxBackoff > 0 {
+		backoff = min(backoff, p.cfg.DeleteMaxBackoff)
+	}
+	return backoff
+}
+
+func (p *Processor) addPendingDeletes(delta int64) {
+	pending := p.pendingDeletes.Add(delta)
+	metrics.BatchBufferSize.WithLabelValues(metrics.BufferDeleteShortlinks).Set(float64(max(pending, 0)))
+}
+
+func (p *Processor) bufferClicksForSave(ctx context.Context) {
+	defer p.wg.Done()
+	defer p.clicksHeartbeat.Store(0)
+
+	var buffer []*entity.Click
+	ticker := time.NewTicker(clicksFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case click := <-p.saveClicksChan:
+			buffer = append(buffer, click)
+		case <-p.stopping:
+			// Clicks that made it into the channel before Stop are saved too
+			for len(p.saveClicksChan) > 0 {
+				buffer = append(buffer, <-p.saveClicksChan)
 			}
+			p.flushClicks(p.stopCtx, buffer)
+			return
+		case <-ticker.C:
+			p.clicksHeartbeat.Store(time.Now().UnixNano())
+			// Clicks still in the channel are waiting too
+			metrics.BatchBufferSize.WithLabelValues(metrics.BufferSaveClicks).Set(float64(len(buffer) + len(p.saveClicksChan)))
+			p.flushClicks(ctx, buffer)
+			buffer = nil
 		}
 	}
 }
+
+func (p *Processor) flushClicks(ctx context.Context, buffer []*entity.Click) {
+	if len(buffer) == 0 {
+		return
+	}
+	err := p.clickRepo.SaveClicks(ctx, buffer)
+	metrics.BatchFlushes.WithLabelValues(metrics.BufferSaveClicks, metrics.Result(err)).Inc()
+	if err != nil {
+		p.log.Error(ctx, err).Msgf("save %d clicks", len(buffer))
+	} else {
+		p.log.Debug(ctx).Msgf("save %d clicks", len(buffer))
+	}
+}
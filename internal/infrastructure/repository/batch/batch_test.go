@@ -0,0 +1,250 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+var log = logger.NewMockLogger()
+
+// flakyRepo fails the first deletes, as an unavailable database would
+type flakyRepo struct {
+	repository.ShortlinkRepo
+	failures atomic.Int32
+}
+
+func (r *flakyRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	if r.failures.Add(-1) >= 0 {
+		return nil, errors.New("connection reset by peer")
+	}
+	return r.ShortlinkRepo.DeleteShortlinks(ctx, userUID, linkUIDs)
+}
+
+func TestProcessorStop(t *testing.T) {
+	ctx := context.Background()
+
+	repo := prepareRepo(t)
+	clickRepo := repository.NewInMemClickRepo(100)
+	deleteQueue, err := repository.NewInMemDeleteQueue("", log)
+	require.NoError(t, err)
+
+	// Flushes never happen on their own, only on Stop
+	p := NewProcessor(ctx, config.Batch{ClicksBufferSize: 10, DeleteFlushInterval: time.Hour}, repo, clickRepo, deleteQueue, log)
+	require.NoError(t, p.Ping(ctx))
+
+	first, err := p.BatchDeleteShortlinks(ctx, "user1", []string{"a", "b"})
+	require.NoError(t, err)
+	// "b" is credited to the first task, "x" is unknown and "c" belongs to another user
+	second, err := p.BatchDeleteShortlinks(ctx, "user2", []string{"c"})
+	require.NoError(t, err)
+	third, err := p.BatchDeleteShortlinks(ctx, "user1", []string{"b", "x"})
+	require.NoError(t, err)
+	p.BatchSaveClick(ctx, &entity.Click{LinkUID: "c", Timestamp: time.Now()})
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, p.Stop(stopCtx))
+
+	page, err := repo.GetShortlinks(ctx, "user1", entity.ShortlinkQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, "c", page.Links[0].UID)
+
+	stats, err := clickRepo.GetClickStats(ctx, "c", entity.ClickStatsQuery{
+		From:   time.Now().Add(-time.Hour),
+		To:     time.Now().Add(time.Hour),
+		Bucket: entity.ClickBucketDay,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Total)
+
+	pending, err := deleteQueue.CountPendingDeleteTasks(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+
+	for _, want := range []struct {
+		task     *entity.DeleteTask
+		deleted  int
+		notFound []string
+	}{
+		{task: first, deleted: 2},
+		{task: second, notFound: []string{"c"}},
+		{task: third, notFound: []string{"b", "x"}},
+	} {
+		task, err := p.GetDeleteTask(ctx, want.task.ID)
+		require.NoError(t, err)
+		require.NotNil(t, task)
+		assert.Equal(t, entity.DeleteTaskDone, task.State())
+		assert.Equal(t, want.deleted, task.Deleted, "task %d", task.ID)
+		assert.Equal(t, want.notFound, task.NotFound, "task %d", task.ID)
+	}
+
+	assert.ErrorIs(t, p.Ping(ctx), ErrProcessorStopped)
+	_, err = p.BatchDeleteShortlinks(ctx, "user1", []string{"c"})
+	assert.ErrorIs(t, err, ErrProcessorStopped)
+	require.NoError(t, p.Stop(stopCtx))
+}
+
+func TestProcessorDeleteRetries(t *testing.T) {
+	type want struct {
+		deleted  bool
+		dead     bool
+		attempts int
+		state    string
+	}
+	tests := []struct {
+		name        string
+		failures    int32
+		maxAttempts int
+		want        want
+	}{
+		{
+			name:        "no failures",
+			maxAttempts: 3,
+			want: want{
+				deleted: true,
+				state:   entity.DeleteTaskDone,
+			},
+		},
+		{
+			name:        "retried until success",
+			failures:    2,
+			maxAttempts: 3,
+			want: want{
+				deleted: true,
+				state:   entity.DeleteTaskDone,
+			},
+		},
+		{
+			name:        "dead-lettered",
+			failures:    100,
+			maxAttempts: 3,
+			want: want{
+				dead:     true,
+				attempts: 3,
+				state:    entity.DeleteTaskFailed,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			repo := &flakyRepo{ShortlinkRepo: prepareRepo(t)}
+			repo.failures.Store(tt.failures)
+			deleteQueue, err := repository.NewInMemDeleteQueue("", log)
+			require.NoError(t, err)
+
+			p := NewProcessor(ctx, config.Batch{
+				DeleteFlushInterval: 5 * time.Millisecond,
+				DeleteBatchSize:     10,
+				DeleteMaxAttempts:   tt.maxAttempts,
+				DeleteRetryBackoff:  time.Millisecond,
+				DeleteMaxBackoff:    5 * time.Millisecond,
+			}, repo, repository.NewInMemClickRepo(100), deleteQueue, log)
+
+			task, err := p.BatchDeleteShortlinks(ctx, "user1", []string{"a"})
+			require.NoError(t, err)
+
+			require.Eventually(t, func() bool {
+				pending, err := deleteQueue.CountPendingDeleteTasks(ctx)
+				return err == nil && pending == 0
+			}, time.Second, 5*time.Millisecond)
+
+			link, err := repo.FindShortlink(ctx, "user1", "a")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.deleted, link == nil)
+
+			task, err = p.GetDeleteTask(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.state, task.State())
+
+			dead, err := deleteQueue.GetDeadDeleteTasks(ctx)
+			require.NoError(t, err)
+			if !tt.want.dead {
+				assert.Empty(t, dead)
+				return
+			}
+			require.Len(t, dead, 1)
+			assert.Equal(t, tt.want.attempts, dead[0].Attempts)
+			assert.Equal(t, []string{"a"}, dead[0].LinkUIDs)
+			assert.NotEmpty(t, dead[0].LastError)
+			assert.NotNil(t, dead[0].DeadAt)
+		})
+	}
+}
+
+func TestProcessorDeleteQueueDepth(t *testing.T) {
+	ctx := context.Background()
+
+	deleteQueue, err := repository.NewInMemDeleteQueue("", log)
+	require.NoError(t, err)
+
+	p := NewProcessor(ctx, config.Batch{DeleteQueueDepth: 2, DeleteFlushInterval: time.Hour}, prepareRepo(t), repository.NewInMemClickRepo(100), deleteQueue, log)
+	defer p.Stop(ctx)
+
+	for _, linkUID := range []string{"a", "b"} {
+		_, err := p.BatchDeleteShortlinks(ctx, "user1", []string{linkUID})
+		require.NoError(t, err)
+	}
+	_, err = p.BatchDeleteShortlinks(ctx, "user1", []string{"c"})
+	assert.ErrorIs(t, err, ErrDeleteQueueFull)
+}
+
+func TestCheckHeartbeat(t *testing.T) {
+	var heartbeat atomic.Int64
+	heartbeat.Store(time.Now().Add(-time.Minute).UnixNano())
+
+	// A minute without a tick is a stall for a loop ticking every second, but not for one ticking every hour
+	assert.Error(t, checkHeartbeat(&heartbeat, time.Second))
+	assert.NoError(t, checkHeartbeat(&heartbeat, time.Hour))
+
+	heartbeat.Store(time.Now().Add(-5 * time.Second).UnixNano())
+	assert.NoError(t, checkHeartbeat(&heartbeat, time.Second))
+
+	heartbeat.Store(0)
+	assert.ErrorIs(t, checkHeartbeat(&heartbeat, time.Second), ErrProcessorStopped)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &Processor{cfg: config.Batch{
+		DeleteRetryBackoff: time.Second,
+		DeleteMaxBackoff:   10 * time.Second,
+	}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 100, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, p.retryBackoff(tt.attempts), "attempts = %d", tt.attempts)
+	}
+}
+
+func prepareRepo(t *testing.T) *repository.InMemShortlinkRepo {
+	repo := repository.NewInMemShortlinkRepo(nil)
+	for _, uid := range []string{"a", "b", "c"} {
+		_, err := repo.SaveShortlink(context.Background(), &entity.Shortlink{UID: uid, UserUID: "user1", Long: "https://example.org/" + uid})
+		require.NoError(t, err)
+	}
+	return repo
+}
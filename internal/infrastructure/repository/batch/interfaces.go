@@ -2,8 +2,12 @@ package batch
 
 import (
 	"context"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 )
 
 type ShortlinkBatchProcessor interface {
-	BatchDeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string)
+	BatchDeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) (*entity.DeleteTask, error)
+	GetDeleteTask(ctx context.Context, taskID int64) (*entity.DeleteTask, error)
+	BatchSaveClick(ctx context.Context, click *entity.Click)
 }
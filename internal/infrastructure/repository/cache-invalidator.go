@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+const (
+	// ShortlinkChangesChannel carries comma-separated UIDs of changed links, or allLinksChanged
+	ShortlinkChangesChannel = "shortlink_changes"
+	allLinksChanged         = "*"
+	// Postgres limits payloads to 8000 bytes
+	maxChangesPayload = 7900
+)
+
+// CacheInvalidator evicts links changed by other instances from the local cache
+type CacheInvalidator struct {
+	cache        *CachedShortlinkRepo
+	source       NotificationSource
+	retryBackoff time.Duration
+	maxBackoff   time.Duration
+	log          *logger.Logger
+}
+
+func NewCacheInvalidator(cache *CachedShortlinkRepo, source NotificationSource, cfg config.Cache, log *logger.Logger) *CacheInvalidator {
+	return &CacheInvalidator{
+		cache:        cache,
+		source:       source,
+		retryBackoff: cfg.ListenRetryBackoff,
+		maxBackoff:   cfg.ListenMaxBackoff,
+		log:          log,
+	}
+}
+
+// Run listens to the changes until the context is done, reconnecting with a backoff
+func (i *CacheInvalidator) Run(ctx context.Context) {
+	for attempts := 1; ; attempts++ {
+		connected, err := i.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			attempts = 1
+		}
+
+		backoff := i.reconnectBackoff(attempts)
+		i.log.Error(ctx, err).Msgf("listen to shortlink changes, reconnecting in %s", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// listen returns when the stream breaks, connected tells whether it was established at all
+func (i *CacheInvalidator) listen(ctx context.Context) (connected bool, err error) {
+	stream, err := i.source.Listen(ctx, ShortlinkChangesChannel)
+	if err != nil {
+		return false, i.log.Wrap(err, "listen")
+	}
+	defer func() {
+		closeErr := stream.Close(context.Background())
+		if closeErr != nil {
+			i.log.Error(ctx, closeErr).Msg("close notification stream")
+		}
+	}()
+
+	// Changes made while disconnected are unknown
+	i.cache.clear()
+	i.log.Info(ctx).Msg("Listening to shortlink changes")
+
+	for {
+		payload, err := stream.Next(ctx)
+		if err != nil {
+			return true, i.log.Wrap(err, "next notification")
+		}
+
+		if payload == allLinksChanged {
+			i.cache.clear()
+		} else {
+			i.cache.invalidate(strings.Split(payload, ",")...)
+		}
+	}
+}
+
+// reconnectBackoff doubles the base backoff on every attempt, up to the max one
+func (i *CacheInvalidator) reconnectBackoff(attempts int) time.Duration {
+	backoff := i.retryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for n := 1; n < attempts; n++ {
+		if i.maxBackoff > 0 && backoff >= i.maxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	if i.maxBackoff > 0 {
+		backoff = min(backoff, i.maxBackoff)
+	}
+	return backoff
+}
+
+// changesPayloads splits UIDs into as few payloads as fit the limit
+func changesPayloads(linkUIDs []string) []string {
+	var (
+		payloads []string
+		payload  strings.Builder
+	)
+	for _, linkUID := range linkUIDs {
+		if payload.Len() > 0 && payload.Len()+1+len(linkUID) > maxChangesPayload {
+			payloads = append(payloads, payload.String())
+			payload.Reset()
+		}
+		if payload.Len() > 0 {
+			payload.WriteByte(',')
+		}
+		payload.WriteString(linkUID)
+	}
+	if payload.Len() > 0 {
+		payloads = append(payloads, payload.String())
+	}
+	return payloads
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+// fakeNotifications fails the first failListens calls to Listen, then hands out streams fed by the test.
+// A stream is passed to the test once it is first read, i.e. the invalidator is ready
+type fakeNotifications struct {
+	mutex       sync.Mutex
+	failListens int
+	listens     int
+	streams     chan *fakeNotificationStream
+}
+
+type fakeNotificationStream struct {
+	ready    sync.Once
+	streams  chan *fakeNotificationStream
+	payloads chan string
+	errs     chan error
+}
+
+func newFakeNotifications(failListens int) *fakeNotifications {
+	return &fakeNotifications{
+		failListens: failListens,
+		streams:     make(chan *fakeNotificationStream, 10),
+	}
+}
+
+func (n *fakeNotifications) Listen(_ context.Context, channel string) (NotificationStream, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.listens++
+	if n.listens <= n.failListens {
+		return nil, errors.New("connection refused")
+	}
+	if channel != ShortlinkChangesChannel {
+		return nil, errors.New("unexpected channel " + channel)
+	}
+
+	return &fakeNotificationStream{streams: n.streams, payloads: make(chan string), errs: make(chan error)}, nil
+}
+
+func (n *fakeNotifications) Listens() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.listens
+}
+
+func (s *fakeNotificationStream) Next(ctx context.Context) (string, error) {
+	s.ready.Do(func() { s.streams <- s })
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case err := <-s.errs:
+		return "", err
+	case payload := <-s.payloads:
+		return payload, nil
+	}
+}
+
+func (s *fakeNotificationStream) Close(context.Context) error {
+	return nil
+}
+
+func TestCacheInvalidator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.Cache{Size: 100, TTL: time.Minute, NegativeTTL: time.Minute, ListenRetryBackoff: time.Millisecond, ListenMaxBackoff: 5 * time.Millisecond}
+	backend := NewInMemShortlinkRepo(nil)
+	cache := NewCachedShortlinkRepo(backend, cfg)
+
+	for _, uid := range []string{"link1", "link2", "link3"} {
+		_, err := backend.SaveShortlink(ctx, &entity.Shortlink{UID: uid, UserUID: "user1", Short: "http://127.0.0.1/" + uid, Long: "https://example.org/" + uid})
+		require.NoError(t, err)
+	}
+	warm := func() {
+		for _, uid := range []string{"link1", "link2", "link3"} {
+			_, err := cache.FindShortlink(ctx, "", uid)
+			require.NoError(t, err)
+		}
+		require.Equal(t, 3, cache.Stats().Entries)
+	}
+
+	notifications := newFakeNotifications(2)
+	invalidator := NewCacheInvalidator(cache, notifications, cfg, logger.NewMockLogger())
+
+	done := make(chan struct{})
+	go func() {
+		invalidator.Run(ctx)
+		close(done)
+	}()
+
+	// Connected after the failed attempts
+	var stream *fakeNotificationStream
+	select {
+	case stream = <-notifications.streams:
+	case <-time.After(time.Second):
+		t.Fatal("not listening")
+	}
+	assert.Equal(t, 3, notifications.Listens())
+
+	t.Run("evicts changed links", func(t *testing.T) {
+		warm()
+		// Changed by another instance
+		_, err := backend.DeleteShortlinks(ctx, "user1", []string{"link1", "link2"})
+		require.NoError(t, err)
+
+		stream.payloads <- "link1,link2"
+		assert.Eventually(t, func() bool { return cache.Stats().Entries == 1 }, time.Second, time.Millisecond)
+
+		found, err := cache.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		assert.True(t, found.Deleted)
+	})
+
+	t.Run("clears on bulk changes", func(t *testing.T) {
+		warm()
+		stream.payloads <- allLinksChanged
+		assert.Eventually(t, func() bool { return cache.Stats().Entries == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("reconnects and clears", func(t *testing.T) {
+		warm()
+		stream.errs <- errors.New("connection reset")
+
+		select {
+		case stream = <-notifications.streams:
+		case <-time.After(time.Second):
+			t.Fatal("not reconnected")
+		}
+		// Changes made while disconnected were missed
+		assert.Zero(t, cache.Stats().Entries)
+
+		warm()
+		stream.payloads <- "link3"
+		assert.Eventually(t, func() bool { return cache.Stats().Entries == 2 }, time.Second, time.Millisecond)
+	})
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("not stopped")
+	}
+}
+
+func TestCacheInvalidatorReconnectBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       time.Duration
+		max        time.Duration
+		attempts   int
+		wantResult time.Duration
+	}{
+		{
+			name:       "first attempt",
+			base:       time.Second,
+			max:        time.Minute,
+			attempts:   1,
+			wantResult: time.Second,
+		},
+		{
+			name:       "doubled",
+			base:       time.Second,
+			max:        time.Minute,
+			attempts:   4,
+			wantResult: 8 * time.Second,
+		},
+		{
+			name:       "capped",
+			base:       time.Second,
+			max:        time.Minute,
+			attempts:   100,
+			wantResult: time.Minute,
+		},
+		{
+			name:       "default base",
+			max:        time.Minute,
+			attempts:   2,
+			wantResult: 2 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalidator := NewCacheInvalidator(nil, nil, config.Cache{ListenRetryBackoff: tt.base, ListenMaxBackoff: tt.max}, logger.NewMockLogger())
+			assert.Equal(t, tt.wantResult, invalidator.reconnectBackoff(tt.attempts))
+		})
+	}
+}
+
+func TestChangesPayloads(t *testing.T) {
+	long := strings.Repeat("a", 3000)
+
+	tests := []struct {
+		name       string
+		linkUIDs   []string
+		wantResult []string
+	}{
+		{
+			name:       "none",
+			linkUIDs:   nil,
+			wantResult: nil,
+		},
+		{
+			name:       "single payload",
+			linkUIDs:   []string{"link1", "link2", "link3"},
+			wantResult: []string{"link1,link2,link3"},
+		},
+		{
+			name:       "split at the limit",
+			linkUIDs:   []string{long, long, long, "link1"},
+			wantResult: []string{long + "," + long, long + ",link1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads := changesPayloads(tt.linkUIDs)
+			assert.Equal(t, tt.wantResult, payloads)
+			for _, payload := range payloads {
+				assert.LessOrEqual(t, len(payload), maxChangesPayload)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
+)
+
+type (
+	// CachedShortlinkRepo keeps links found by UID (and UIDs not found) in a bounded LRU cache, for the redirects.
+	// Changes made through it invalidate the cache, changes made elsewhere are seen once the entries expire
+	CachedShortlinkRepo struct {
+		repo        ShortlinkRepo
+		size        int
+		ttl         time.Duration
+		negativeTTL time.Duration
+
+		mutex   sync.Mutex
+		entries map[string]*list.Element
+		// Most recently used first
+		lru *list.List
+		// Lookups of the repo in progress, so that a link invalidated while being read is not cached
+		reads map[string]*pendingRead
+		// generation changes when the whole cache is cleared, which invalidates all reads in progress
+		generation uint64
+
+		hits   atomic.Int64
+		misses atomic.Int64
+
+		now func() time.Time
+	}
+	CacheStats struct {
+		Hits    int64
+		Misses  int64
+		Entries int
+	}
+	cacheEntry struct {
+		linkUID string
+		// nil if there is no such link
+		link      *entity.Shortlink
+		expiresAt time.Time
+	}
+	pendingRead struct {
+		readers int
+		// version changes when the UID is invalidated
+		version uint64
+	}
+	// readToken is taken on a miss and passed to put, to tell whether the UID was invalidated since
+	readToken struct {
+		generation uint64
+		version    uint64
+	}
+)
+
+func NewCachedShortlinkRepo(repo ShortlinkRepo, cfg config.Cache) *CachedShortlinkRepo {
+	return &CachedShortlinkRepo{
+		repo:        repo,
+		size:        cfg.Size,
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		reads:       make(map[string]*pendingRead),
+		now:         time.Now,
+	}
+}
+
+func (r *CachedShortlinkRepo) Stats() CacheStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return CacheStats{
+		Hits:    r.hits.Load(),
+		Misses:  r.misses.Load(),
+		Entries: r.lru.Len(),
+	}
+}
+
+func (r *CachedShortlinkRepo) SaveShortlink(ctx context.Context, link *entity.Shortlink) (*entity.Shortlink, error) {
+	// The UID may be cached as unknown
+	defer r.invalidate(link.UID)
+	return r.repo.SaveShortlink(ctx, link)
+}
+
+// FindShortlink is cached only for lookups by UID alone, those of the user's own links go to the repo
+func (r *CachedShortlinkRepo) FindShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error) {
+	if userUID != "" {
+		return r.repo.FindShortlink(ctx, userUID, linkUID)
+	}
+
+	link, token, ok := r.get(linkUID)
+	if ok {
+		r.hits.Add(1)
+		metrics.CacheRequests.WithLabelValues(metrics.CacheHit).Inc()
+		return link, nil
+	}
+	r.misses.Add(1)
+	metrics.CacheRequests.WithLabelValues(metrics.CacheMiss).Inc()
+
+	link, err := r.repo.FindShortlink(ctx, userUID, linkUID)
+	if err != nil {
+		r.abort(linkUID)
+		return nil, err
+	}
+
+	r.put(linkUID, link, token)
+	return link, nil
+}
+
+func (r *CachedShortlinkRepo) SaveShortlinks(ctx context.Context, links []*entity.Shortlink) ([]*entity.Shortlink, error) {
+	linkUIDs := make([]string, len(links))
+	for i, link := range links {
+		linkUIDs[i] = link.UID
+	}
+	defer r.invalidate(linkUIDs...)
+
+	return r.repo.SaveShortlinks(ctx, links)
+}
+
+func (r *CachedShortlinkRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([]*entity.Shortlink, error) {
+	return r.repo.FindShortlinks(ctx, linkUIDs)
+}
+
+func (r *CachedShortlinkRepo) GetShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (*entity.ShortlinkPage, error) {
+	return r.repo.GetShortlinks(ctx, userUID, query)
+}
+
+func (r *CachedShortlinkRepo) UpdateShortlinkLong(ctx context.Context, userUID, linkUID, long string) (*entity.Shortlink, error) {
+	defer r.invalidate(linkUID)
+	return r.repo.UpdateShortlinkLong(ctx, userUID, linkUID, long)
+}
+
+func (r *CachedShortlinkRepo) GetShortlinkRevisions(ctx context.Context, linkUID string) ([]*entity.ShortlinkRevision, error) {
+	return r.repo.GetShortlinkRevisions(ctx, linkUID)
+}
+
+func (r *CachedShortlinkRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	defer r.invalidate(linkUIDs...)
+	return r.repo.DeleteShortlinks(ctx, userUID, linkUIDs)
+}
+
+func (r *CachedShortlinkRepo) UndeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	defer r.invalidate(linkUIDs...)
+	return r.repo.UndeleteShortlinks(ctx, userUID, linkUIDs)
+}
+
+func (r *CachedShortlinkRepo) GetDeletedShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error) {
+	return r.repo.GetDeletedShortlinks(ctx, userUID)
+}
+
+// PurgeDeletedShortlinks does not tell which links are gone, so the whole cache is dropped if any are
+func (r *CachedShortlinkRepo) PurgeDeletedShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	purged, err := r.repo.PurgeDeletedShortlinks(ctx, before)
+	r.clearIfChanged(purged, err)
+	return purged, err
+}
+
+func (r *CachedShortlinkRepo) DeleteExpiredShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := r.repo.DeleteExpiredShortlinks(ctx, before)
+	r.clearIfChanged(deleted, err)
+	return deleted, err
+}
+
+func (r *CachedShortlinkRepo) CountShortlinks(ctx context.Context) (int64, error) {
+	return r.repo.CountShortlinks(ctx)
+}
+
+func (r *CachedShortlinkRepo) IterateShortlinks(ctx context.Context, fn func(link *entity.Shortlink) error) error {
+	return r.repo.IterateShortlinks(ctx, fn)
+}
+
+func (r *CachedShortlinkRepo) ImportShortlinks(ctx context.Context, links []*entity.Shortlink) (int64, error) {
+	imported, err := r.repo.ImportShortlinks(ctx, links)
+	r.clearIfChanged(imported, err)
+	return imported, err
+}
+
+func (r *CachedShortlinkRepo) Ping(ctx context.Context) error {
+	return r.repo.Ping(ctx)
+}
+
+func (r *CachedShortlinkRepo) Backup(ctx context.Context) error {
+	return r.repo.Backup(ctx)
+}
+
+func (r *CachedShortlinkRepo) Restore(ctx context.Context) error {
+	defer r.clear()
+	return r.repo.Restore(ctx)
+}
+
+func (r *CachedShortlinkRepo) Close(ctx context.Context) error {
+	defer r.clear()
+	return r.repo.Close(ctx)
+}
+
+// get returns the cached link, or the token to pass to put (or abort) after reading it from the repo
+func (r *CachedShortlinkRepo) get(linkUID string) (_ *entity.Shortlink, token readToken, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	element, ok := r.entries[linkUID]
+	if ok {
+		entry := element.Value.(*cacheEntry)
+		if r.now().Before(entry.expiresAt) {
+			r.lru.MoveToFront(element)
+			return entry.link, readToken{}, true
+		}
+		r.remove(element)
+	}
+
+	read, ok := r.reads[linkUID]
+	if !ok {
+		read = &pendingRead{}
+		r.reads[linkUID] = read
+	}
+	read.readers++
+	return nil, readToken{generation: r.generation, version: read.version}, false
+}
+
+func (r *CachedShortlinkRepo) put(linkUID string, link *entity.Shortlink, token readToken) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Invalidated while being read
+	if !r.finishRead(linkUID, token) {
+		return
+	}
+
+	ttl := r.ttl
+	if link == nil {
+		ttl = r.negativeTTL
+	}
+	if ttl <= 0 || r.size <= 0 {
+		return
+	}
+
+	entry := &cacheEntry{
+		linkUID:   linkUID,
+		link:      link,
+		expiresAt: r.now().Add(ttl),
+	}
+
+	if element, ok := r.entries[linkUID]; ok {
+		element.Value = entry
+		r.lru.MoveToFront(element)
+		return
+	}
+
+	r.entries[linkUID] = r.lru.PushFront(entry)
+	if r.lru.Len() > r.size {
+		r.remove(r.lru.Back())
+	}
+	metrics.CacheEntries.Set(float64(r.lru.Len()))
+}
+
+// abort ends a read that failed, nothing is cached
+func (r *CachedShortlinkRepo) abort(linkUID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.finishRead(linkUID, readToken{})
+}
+
+// finishRead must be called with the mutex held, returns false if the UID was invalidated since the token was taken
+func (r *CachedShortlinkRepo) finishRead(linkUID string, token readToken) bool {
+	read := r.reads[linkUID]
+	read.readers--
+	if read.readers == 0 {
+		delete(r.reads, linkUID)
+	}
+	return token.generation == r.generation && token.version == read.version
+}
+
+// invalidate only affects the given UIDs, reads of other links in progress are cached as usual
+func (r *CachedShortlinkRepo) invalidate(linkUIDs ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, linkUID := range linkUIDs {
+		if element, ok := r.entries[linkUID]; ok {
+			r.remove(element)
+		}
+		if read, ok := r.reads[linkUID]; ok {
+			read.version++
+		}
+	}
+	metrics.CacheEntries.Set(float64(r.lru.Len()))
+}
+
+func (r *CachedShortlinkRepo) clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	r.entries = make(map[string]*list.Element)
+	r.lru.Init()
+	metrics.CacheEntries.Set(0)
+}
+
+// clearIfChanged drops the cache after a bulk change that touched any links
+func (r *CachedShortlinkRepo) clearIfChanged(affected int64, err error) {
+	if err == nil && affected > 0 {
+		r.clear()
+	}
+}
+
+// remove must be called with the mutex held
+func (r *CachedShortlinkRepo) remove(element *list.Element) {
+	r.lru.Remove(element)
+	delete(r.entries, element.Value.(*cacheEntry).linkUID)
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+)
+
+func TestCachedShortlinkRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCachedShortlinkRepo(NewInMemShortlinkRepo(nil), config.Cache{Size: 100, TTL: time.Minute, NegativeTTL: time.Second})
+
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+
+	_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org"})
+	require.NoError(t, err)
+
+	t.Run("miss then hit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			found, err := repo.FindShortlink(ctx, "", "link1")
+			require.NoError(t, err)
+			require.NotNil(t, found)
+			assert.Equal(t, "https://example.org", found.Long)
+		}
+		assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Entries: 1}, repo.Stats())
+
+		// Lookups of the user's own links are not cached
+		_, err := repo.FindShortlink(ctx, "user1", "link1")
+		require.NoError(t, err)
+		assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Entries: 1}, repo.Stats())
+	})
+
+	t.Run("unknown UID", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			found, err := repo.FindShortlink(ctx, "", "link2")
+			require.NoError(t, err)
+			assert.Nil(t, found)
+		}
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 2, Entries: 2}, repo.Stats())
+
+		_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: "link2", UserUID: "user1", Short: "http://127.0.0.1/link2", Long: "https://google.com"})
+		require.NoError(t, err)
+
+		found, err := repo.FindShortlink(ctx, "", "link2")
+		require.NoError(t, err)
+		assert.NotNil(t, found)
+	})
+
+	t.Run("invalidated on changes", func(t *testing.T) {
+		_, err := repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.net")
+		require.NoError(t, err)
+
+		found, err := repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.net", found.Long)
+
+		_, err = repo.DeleteShortlinks(ctx, "user1", []string{"link1"})
+		require.NoError(t, err)
+
+		found, err = repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		assert.True(t, found.Deleted)
+
+		_, err = repo.UndeleteShortlinks(ctx, "user1", []string{"link1"})
+		require.NoError(t, err)
+
+		found, err = repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		assert.False(t, found.Deleted)
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		before := repo.Stats()
+
+		_, err := repo.FindShortlink(ctx, "", "link3")
+		require.NoError(t, err)
+
+		// Unknown UIDs are remembered for less time
+		now = now.Add(2 * time.Second)
+		_, err = repo.FindShortlink(ctx, "", "link3")
+		require.NoError(t, err)
+		_, err = repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+
+		now = now.Add(time.Minute)
+		_, err = repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+
+		after := repo.Stats()
+		assert.Equal(t, int64(1), after.Hits-before.Hits)
+		assert.Equal(t, int64(3), after.Misses-before.Misses)
+	})
+
+	t.Run("kept by bulk changes that affect nothing", func(t *testing.T) {
+		_, err := repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		before := repo.Stats().Entries
+		require.NotZero(t, before)
+
+		purged, err := repo.PurgeDeletedShortlinks(ctx, time.Now())
+		require.NoError(t, err)
+		require.Zero(t, purged)
+		deleted, err := repo.DeleteExpiredShortlinks(ctx, time.Now())
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+		assert.Equal(t, before, repo.Stats().Entries)
+	})
+
+	t.Run("cleared by bulk changes", func(t *testing.T) {
+		_, err := repo.DeleteShortlinks(ctx, "user1", []string{"link2"})
+		require.NoError(t, err)
+		_, err = repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		require.NotZero(t, repo.Stats().Entries)
+
+		purged, err := repo.PurgeDeletedShortlinks(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), purged)
+		assert.Zero(t, repo.Stats().Entries)
+	})
+}
+
+func TestCachedShortlinkRepoEviction(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCachedShortlinkRepo(NewInMemShortlinkRepo(nil), config.Cache{Size: 2, TTL: time.Minute})
+
+	for i := 1; i <= 3; i++ {
+		uid := fmt.Sprintf("link%d", i)
+		_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: uid, UserUID: "user1", Short: "http://127.0.0.1/" + uid, Long: "https://example.org/" + uid})
+		require.NoError(t, err)
+	}
+
+	// link1 is used more recently than link2, so link2 is evicted by link3
+	for _, uid := range []string{"link1", "link2", "link1", "link3"} {
+		_, err := repo.FindShortlink(ctx, "", uid)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3, Entries: 2}, repo.Stats())
+
+	for _, uid := range []string{"link1", "link3", "link2"} {
+		_, err := repo.FindShortlink(ctx, "", uid)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 4, Entries: 2}, repo.Stats())
+}
+
+func TestCachedShortlinkRepoConcurrency(t *testing.T) {
+	ctx := context.Background()
+	backend := NewInMemShortlinkRepo(nil)
+	repo := NewCachedShortlinkRepo(backend, config.Cache{Size: 5, TTL: time.Minute, NegativeTTL: time.Minute})
+
+	const links = 10
+	uids := make([]string, links)
+	for i := range uids {
+		uids[i] = fmt.Sprintf("link%d", i)
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+
+			for i := 0; i < 1000; i++ {
+				uid := uids[rng.Intn(links)]
+				var err error
+				switch rng.Intn(5) {
+				case 0:
+					_, err = repo.SaveShortlink(ctx, &entity.Shortlink{UID: uid, UserUID: "user1", Short: "http://127.0.0.1/" + uid, Long: "https://example.org/" + uid})
+					if errors.Is(err, ErrUIDConflict) {
+						err = nil
+					}
+				case 1:
+					_, err = repo.DeleteShortlinks(ctx, "user1", []string{uid})
+				case 2:
+					_, err = repo.UndeleteShortlinks(ctx, "user1", []string{uid})
+				default:
+					_, err = repo.FindShortlink(ctx, "", uid)
+				}
+				assert.NoError(t, err)
+			}
+		}(int64(worker))
+	}
+	wg.Wait()
+
+	// Whatever is cached matches the backend
+	for _, uid := range uids {
+		cached, err := repo.FindShortlink(ctx, "", uid)
+		require.NoError(t, err)
+		actual, err := backend.FindShortlink(ctx, "", uid)
+		require.NoError(t, err)
+		assert.Equal(t, actual, cached, uid)
+	}
+}
+
+// slowFindRepo holds FindShortlink after reading the link, until proceed is closed
+type slowFindRepo struct {
+	*InMemShortlinkRepo
+	read    chan struct{}
+	proceed chan struct{}
+}
+
+func (r *slowFindRepo) FindShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error) {
+	link, err := r.InMemShortlinkRepo.FindShortlink(ctx, userUID, linkUID)
+	close(r.read)
+	<-r.proceed
+	return link, err
+}
+
+func TestCachedShortlinkRepoStaleRead(t *testing.T) {
+	ctx := context.Background()
+	backend := &slowFindRepo{InMemShortlinkRepo: NewInMemShortlinkRepo(nil), read: make(chan struct{}), proceed: make(chan struct{})}
+	repo := NewCachedShortlinkRepo(backend, config.Cache{Size: 10, TTL: time.Minute})
+
+	_, err := backend.SaveShortlink(ctx, &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org"})
+	require.NoError(t, err)
+
+	stale := make(chan *entity.Shortlink)
+	go func() {
+		link, _ := repo.FindShortlink(ctx, "", "link1")
+		stale <- link
+	}()
+
+	// Deleted after the reader got the link, but before it is cached
+	<-backend.read
+	_, err = repo.DeleteShortlinks(ctx, "user1", []string{"link1"})
+	require.NoError(t, err)
+	close(backend.proceed)
+
+	assert.False(t, (<-stale).Deleted)
+
+	backend.read = make(chan struct{})
+	found, err := repo.FindShortlink(ctx, "", "link1")
+	require.NoError(t, err)
+	assert.True(t, found.Deleted)
+}
+
+func TestCachedShortlinkRepoUnrelatedWrite(t *testing.T) {
+	ctx := context.Background()
+	backend := &slowFindRepo{InMemShortlinkRepo: NewInMemShortlinkRepo(nil), read: make(chan struct{}), proceed: make(chan struct{})}
+	repo := NewCachedShortlinkRepo(backend, config.Cache{Size: 10, TTL: time.Minute})
+
+	_, err := backend.SaveShortlink(ctx, &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org"})
+	require.NoError(t, err)
+
+	found := make(chan *entity.Shortlink)
+	go func() {
+		link, _ := repo.FindShortlink(ctx, "", "link1")
+		found <- link
+	}()
+
+	// Another link is created while link1 is being read, link1 must still be cached
+	<-backend.read
+	_, err = repo.SaveShortlink(ctx, &entity.Shortlink{UID: "link2", UserUID: "user1", Short: "http://127.0.0.1/link2", Long: "https://google.com"})
+	require.NoError(t, err)
+	close(backend.proceed)
+
+	require.NotNil(t, <-found)
+	assert.Equal(t, CacheStats{Hits: 0, Misses: 1, Entries: 1}, repo.Stats())
+
+	_, err = repo.FindShortlink(ctx, "", "link1")
+	require.NoError(t, err)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Entries: 1}, repo.Stats())
+}
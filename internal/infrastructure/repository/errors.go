@@ -4,4 +4,8 @@ import "errors"
 
 var (
 	ErrURLConflict = errors.New("long URL already exists")
+	ErrUIDConflict = errors.New("link UID already exists")
+
+	ErrUnknownBackend       = errors.New("unknown storage backend")
+	ErrBackendMisconfigured = errors.New("storage backend is misconfigured")
 )
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+)
+
+// InMemClickRepo keeps the most recent clicks in a fixed-size ring, older clicks are overwritten
+type InMemClickRepo struct {
+	clicks []*entity.Click
+	next   int
+	mutex  sync.RWMutex
+}
+
+func NewInMemClickRepo(capacity int) *InMemClickRepo {
+	return &InMemClickRepo{
+		clicks: make([]*entity.Click, capacity),
+		mutex:  sync.RWMutex{},
+	}
+}
+
+func (r *InMemClickRepo) SaveClicks(ctx context.Context, clicks []*entity.Click) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.clicks) == 0 {
+		return nil
+	}
+
+	for _, click := range clicks {
+		r.clicks[r.next] = click
+		r.next = (r.next + 1) % len(r.clicks)
+	}
+	return nil
+}
+
+func (r *InMemClickRepo) GetClickStats(ctx context.Context, linkUID string, query entity.ClickStatsQuery) (*entity.ClickStats, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stats := new(entity.ClickStats)
+	visitors := make(map[string]struct{})
+	buckets := make(map[time.Time]int64)
+	referrers := make(map[string]int64)
+	userAgents := make(map[string]int64)
+
+	for _, click := range r.clicks {
+		if click == nil || click.LinkUID != linkUID {
+			continue
+		}
+		if click.Timestamp.Before(query.From) || !click.Timestamp.Before(query.To) {
+			continue
+		}
+
+		stats.Total++
+		visitors[click.IP+"|"+click.UserAgent] = struct{}{}
+		buckets[query.BucketStart(click.Timestamp)]++
+		if click.Referrer != "" {
+			referrers[click.Referrer]++
+		}
+		if click.UserAgent != "" {
+			userAgents[click.UserAgent]++
+		}
+	}
+
+	stats.UniqueVisitors = int64(len(visitors))
+
+	for start, count := range buckets {
+		stats.Buckets = append(stats.Buckets, entity.ClickBucket{Start: start, Count: count})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool {
+		return stats.Buckets[i].Start.Before(stats.Buckets[j].Start)
+	})
+
+	stats.TopReferrers = topClickCounters(referrers, query.Top)
+	stats.TopUserAgents = topClickCounters(userAgents, query.Top)
+
+	return stats, nil
+}
+
+func topClickCounters(counts map[string]int64, limit int) []entity.ClickCounter {
+	counters := make([]entity.ClickCounter, 0, len(counts))
+	for value, count := range counts {
+		counters = append(counters, entity.ClickCounter{Value: value, Count: count})
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Count == counters[j].Count {
+			return counters[i].Value < counters[j].Value
+		}
+		return counters[i].Count > counters[j].Count
+	})
+	if len(counters) > limit {
+		counters = counters[:limit]
+	}
+	return counters
+}
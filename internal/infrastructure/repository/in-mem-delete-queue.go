@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+const (
+	deleteQueueOpPut    = "put"
+	deleteQueueOpRemove = "remove"
+	// deleteQueueOpNextID starts a compacted journal, so that task IDs are not reused after a restart
+	deleteQueueOpNextID = "next_id"
+)
+
+type (
+	// InMemDeleteQueue keeps delete tasks in memory. If path is set, every change is also appended
+	// to a journal file (and fsynced, deletes are rare enough), which is replayed on startup
+	InMemDeleteQueue struct {
+		path   string
+		tasks  map[int64]*entity.DeleteTask
+		nextID int64
+		mutex  sync.Mutex
+		log    *logger.Logger
+	}
+	deleteQueueEntry struct {
+		Op      string             `json:"op"`
+		Task    *entity.DeleteTask `json:"task,omitempty"`
+		TaskIDs []int64            `json:"ids,omitempty"`
+		NextID  int64              `json:"next_id,omitempty"`
+	}
+)
+
+func NewInMemDeleteQueue(path string, log *logger.Logger) (*InMemDeleteQueue, error) {
+	q := &InMemDeleteQueue{
+		path:   path,
+		tasks:  make(map[int64]*entity.DeleteTask),
+		nextID: 1,
+		log:    log,
+	}
+	if path == "" {
+		return q, nil
+	}
+
+	err := q.replay()
+	if err != nil {
+		return nil, log.Wrap(err, "replay delete queue journal")
+	}
+	// Drops purged tasks and a possibly torn last line
+	err = q.rewrite()
+	if err != nil {
+		return nil, log.Wrap(err, "compact delete queue journal")
+	}
+	return q, nil
+}
+
+func (q *InMemDeleteQueue) EnqueueDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	stored := *task
+	stored.ID = q.nextID
+
+	err := q.append(deleteQueueEntry{Op: deleteQueueOpPut, Task: &stored})
+	if err != nil {
+		return err
+	}
+
+	q.nextID++
+	q.tasks[stored.ID] = &stored
+	task.ID = stored.ID
+	return nil
+}
+
+func (q *InMemDeleteQueue) GetDeleteTask(ctx context.Context, taskID int64) (*entity.DeleteTask, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	task, ok := q.tasks[taskID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *task
+	return &copied, nil
+}
+
+func (q *InMemDeleteQueue) GetDueDeleteTasks(ctx context.Context, now time.Time, limit int) ([]*entity.DeleteTask, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var due []*entity.DeleteTask
+	for _, task := range q.tasks {
+		if task.IsPending() && !task.NextAttemptAt.After(now) {
+			copied := *task
+			due = append(due, &copied)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (q *InMemDeleteQueue) CountPendingDeleteTasks(ctx context.Context) (int64, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var pending int64
+	for _, task := range q.tasks {
+		if task.IsPending() {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (q *InMemDeleteQueue) CompleteDeleteTasks(ctx context.Context, tasks []*entity.DeleteTask) error {
+	return q.update(tasks...)
+}
+
+func (q *InMemDeleteQueue) RetryDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	return q.update(task)
+}
+
+func (q *InMemDeleteQueue) DeadLetterDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	return q.update(task)
+}
+
+func (q *InMemDeleteQueue) GetDeadDeleteTasks(ctx context.Context) ([]*entity.DeleteTask, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var dead []*entity.DeleteTask
+	for _, task := range q.tasks {
+		if task.DeadAt != nil {
+			copied := *task
+			dead = append(dead, &copied)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool { return dead[i].ID < dead[j].ID })
+
+	return dead, nil
+}
+
+func (q *InMemDeleteQueue) PurgeDeleteTasks(ctx context.Context, before time.Time) (int64, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var taskIDs []int64
+	for id, task := range q.tasks {
+		if task.DoneAt != nil && task.DoneAt.Before(before) {
+			taskIDs = append(taskIDs, id)
+		}
+	}
+	if len(taskIDs) == 0 {
+		return 0, nil
+	}
+
+	err := q.append(deleteQueueEntry{Op: deleteQueueOpRemove, TaskIDs: taskIDs})
+	if err != nil {
+		return 0, err
+	}
+	for _, taskID := range taskIDs {
+		delete(q.tasks, taskID)
+	}
+
+	// The journal only grows otherwise, purges are rare enough to compact it every time
+	err = q.rewrite()
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(taskIDs)), nil
+}
+
+// update replaces stored tasks with copies of the given ones, unknown (e.g. purged) tasks are ignored
+func (q *InMemDeleteQueue) update(tasks ...*entity.DeleteTask) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var entries []deleteQueueEntry
+	for _, task := range tasks {
+		if _, ok := q.tasks[task.ID]; !ok {
+			continue
+		}
+		stored := *task
+		entries = append(entries, deleteQueueEntry{Op: deleteQueueOpPut, Task: &stored})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	err := q.append(entries...)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		q.tasks[entry.Task.ID] = entry.Task
+	}
+	return nil
+}
+
+func (q *InMemDeleteQueue) append(entries ...deleteQueueEntry) error {
+	if q.path == "" {
+		return nil
+	}
+
+	var lines []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(lines)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// replay folds the journal into the current set of tasks, stopping at a torn line (e.g. crash mid-append)
+func (q *InMemDeleteQueue) replay() error {
+	file, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var entry deleteQueueEntry
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			q.log.Error(context.Background(), err).Msg("decode delete queue entry, ignoring the rest of the journal")
+			return nil
+		}
+
+		switch entry.Op {
+		case deleteQueueOpPut:
+			if entry.Task == nil {
+				continue
+			}
+			q.tasks[entry.Task.ID] = entry.Task
+			if entry.Task.ID >= q.nextID {
+				q.nextID = entry.Task.ID + 1
+			}
+		case deleteQueueOpRemove:
+			for _, taskID := range entry.TaskIDs {
+				delete(q.tasks, taskID)
+			}
+		case deleteQueueOpNextID:
+			q.nextID = max(q.nextID, entry.NextID)
+		}
+	}
+}
+
+// rewrite atomically replaces the journal with the current set of tasks
+func (q *InMemDeleteQueue) rewrite() error {
+	if q.path == "" {
+		return nil
+	}
+
+	tmpPath := q.path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(q.tasks))
+	for id := range q.tasks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	if err := encoder.Encode(deleteQueueEntry{Op: deleteQueueOpNextID, NextID: q.nextID}); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	for _, id := range ids {
+		if err := encoder.Encode(deleteQueueEntry{Op: deleteQueueOpPut, Task: q.tasks[id]}); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, q.path)
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestInMemDeleteQueueJournal(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewMockLogger()
+	path := filepath.Join(t.TempDir(), "shortener.deletes")
+	now := time.Now().UTC()
+
+	q, err := NewInMemDeleteQueue(path, log)
+	require.NoError(t, err)
+
+	first := &entity.DeleteTask{UserUID: "user1", LinkUIDs: []string{"link1", "link2"}, NextAttemptAt: now, CreatedAt: now}
+	second := &entity.DeleteTask{UserUID: "user1", LinkUIDs: []string{"link3"}, NextAttemptAt: now, CreatedAt: now}
+	require.NoError(t, q.EnqueueDeleteTask(ctx, first))
+	require.NoError(t, q.EnqueueDeleteTask(ctx, second))
+
+	doneAt := now.Add(-time.Hour)
+	first.Attempts = 1
+	first.Deleted = 1
+	first.NotFound = []string{"link2"}
+	first.DoneAt = &doneAt
+	require.NoError(t, q.CompleteDeleteTasks(ctx, []*entity.DeleteTask{first}))
+
+	second.Attempts = 1
+	second.LastError = "connection reset by peer"
+	require.NoError(t, q.RetryDeleteTask(ctx, second))
+
+	t.Run("replayed after restart", func(t *testing.T) {
+		q, err := NewInMemDeleteQueue(path, log)
+		require.NoError(t, err)
+
+		due, err := q.GetDueDeleteTasks(ctx, now, 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, second.ID, due[0].ID)
+		assert.Equal(t, 1, due[0].Attempts)
+		assert.Equal(t, "connection reset by peer", due[0].LastError)
+
+		done, err := q.GetDeleteTask(ctx, first.ID)
+		require.NoError(t, err)
+		require.NotNil(t, done)
+		assert.Equal(t, entity.DeleteTaskDone, done.State())
+		assert.Equal(t, 1, done.Deleted)
+		assert.Equal(t, []string{"link2"}, done.NotFound)
+	})
+
+	t.Run("torn last line is ignored", func(t *testing.T) {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"op":"remove","ids":[`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		q, err := NewInMemDeleteQueue(path, log)
+		require.NoError(t, err)
+
+		pending, err := q.CountPendingDeleteTasks(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), pending)
+	})
+
+	t.Run("purged and IDs are not reused", func(t *testing.T) {
+		q, err := NewInMemDeleteQueue(path, log)
+		require.NoError(t, err)
+
+		purged, err := q.PurgeDeleteTasks(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		q, err = NewInMemDeleteQueue(path, log)
+		require.NoError(t, err)
+
+		task, err := q.GetDeleteTask(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Nil(t, task)
+
+		third := &entity.DeleteTask{UserUID: "user1", LinkUIDs: []string{"link4"}, NextAttemptAt: now, CreatedAt: now}
+		require.NoError(t, q.EnqueueDeleteTask(ctx, third))
+		assert.Greater(t, third.ID, second.ID)
+	})
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"io"
 	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/storage"
@@ -15,14 +17,23 @@ type InMemShortlinkRepo struct {
 	backup storage.Storage
 	//		  userUID   linkUID
 	links map[string]map[string]*entity.Shortlink
-	mutex sync.RWMutex
+	// Revisions (oldest first) are backed up along with their links
+	//             linkUID
+	revisions map[string][]*entity.ShortlinkRevision
+	mutex     sync.RWMutex
 }
 
 func NewInMemShortlinkRepo(backup storage.Storage) *InMemShortlinkRepo {
+	if backup == nil {
+		// Zero FileStorage does nothing, links are kept in memory only
+		backup = &storage.FileStorage{}
+	}
+
 	return &InMemShortlinkRepo{
-		backup: backup,
-		links:  make(map[string]map[string]*entity.Shortlink),
-		mutex:  sync.RWMutex{},
+		backup:    backup,
+		links:     make(map[string]map[string]*entity.Shortlink),
+		revisions: make(map[string][]*entity.ShortlinkRevision),
+		mutex:     sync.RWMutex{},
 	}
 }
 
@@ -31,28 +42,59 @@ func (r *InMemShortlinkRepo) Ping(ctx context.Context) error {
 }
 
 func (r *InMemShortlinkRepo) SaveShortlink(ctx context.Context, link *entity.Shortlink) (*entity.Shortlink, error) {
+	_, err := r.SaveShortlinks(ctx, []*entity.Shortlink{link})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// SaveShortlinks saves either all links or none of them, as the Postgres transaction does
+func (r *InMemShortlinkRepo) SaveShortlinks(ctx context.Context, links []*entity.Shortlink) ([]*entity.Shortlink, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, ok := r.links[link.UserUID]; !ok {
-		r.links[link.UserUID] = make(map[string]*entity.Shortlink)
+	seen := make(map[string]bool, len(links))
+	for _, link := range links {
+		if seen[link.UID] || r.hasLink(link.UID) {
+			return nil, ErrUIDConflict
+		}
+		seen[link.UID] = true
 	}
 
-	r.links[link.UserUID][link.UID] = link
-
-	return link, nil
-}
+	// Journal first, so that an acknowledged link is never lost
+	err := r.backup.AppendSaved(ctx, links)
+	if err != nil {
+		return nil, err
+	}
 
-func (r *InMemShortlinkRepo) SaveShortlinks(ctx context.Context, links []*entity.Shortlink) ([]*entity.Shortlink, error) {
 	for _, link := range links {
-		if _, err := r.SaveShortlink(ctx, link); err != nil {
-			return nil, err
+		// Imported links may come with their history
+		if len(link.Revisions) > 0 {
+			r.revisions[link.UID] = link.Revisions
+			copied := *link
+			copied.Revisions = nil
+			link = &copied
+		}
+		if _, ok := r.links[link.UserUID]; !ok {
+			r.links[link.UserUID] = make(map[string]*entity.Shortlink)
 		}
+		r.links[link.UserUID][link.UID] = link
 	}
 
 	return links, nil
 }
 
+// hasLink must be called with the mutex held
+func (r *InMemShortlinkRepo) hasLink(linkUID string) bool {
+	for _, userLinks := range r.links {
+		if _, ok := userLinks[linkUID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *InMemShortlinkRepo) FindShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
@@ -71,7 +113,11 @@ func (r *InMemShortlinkRepo) FindShortlink(ctx context.Context, userUID, linkUID
 		return nil, nil
 	}
 
-	return r.links[userUID][linkUID], nil
+	link, ok := r.links[userUID][linkUID]
+	if !ok || link.Deleted {
+		return nil, nil
+	}
+	return link, nil
 }
 
 func (r *InMemShortlinkRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([]*entity.Shortlink, error) {
@@ -93,36 +139,230 @@ func (r *InMemShortlinkRepo) FindShortlinks(ctx context.Context, linkUIDs []stri
 	return links, nil
 }
 
-func (r *InMemShortlinkRepo) GetShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error) {
+func (r *InMemShortlinkRepo) GetShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (*entity.ShortlinkPage, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	if _, ok := r.links[userUID]; !ok {
+	var links []*entity.Shortlink
+
+	for _, link := range r.links[userUID] {
+		if !link.Deleted && query.Matches(link) {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return query.Less(query.CursorAt(links[i]), query.CursorAt(links[j]))
+	})
+	if query.Limit > 0 && len(links) > query.Limit+1 {
+		links = links[:query.Limit+1]
+	}
+
+	return newShortlinkPage(query, links), nil
+}
+
+func (r *InMemShortlinkRepo) CountShortlinks(ctx context.Context) (int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var count int64
+	for _, userLinks := range r.links {
+		count += int64(len(userLinks))
+	}
+	return count, nil
+}
+
+func (r *InMemShortlinkRepo) IterateShortlinks(ctx context.Context, fn func(link *entity.Shortlink) error) error {
+	// Collect first, so that fn is free to call the repo
+	r.mutex.RLock()
+	var links []*entity.Shortlink
+	for _, userLinks := range r.links {
+		for _, link := range userLinks {
+			links = append(links, r.withRevisions(link))
+		}
+	}
+	r.mutex.RUnlock()
+
+	for _, link := range links {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *InMemShortlinkRepo) ImportShortlinks(ctx context.Context, links []*entity.Shortlink) (int64, error) {
+	var imported int64
+
+	for _, link := range links {
+		_, err := r.SaveShortlink(ctx, link)
+		if errors.Is(err, ErrUIDConflict) {
+			continue
+		}
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+func (r *InMemShortlinkRepo) UpdateShortlinkLong(ctx context.Context, userUID, linkUID, long string) (*entity.Shortlink, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	link, ok := r.links[userUID][linkUID]
+	if !ok || link.Deleted {
 		return nil, nil
 	}
+	if link.Long == long {
+		return link, nil
+	}
+	for _, userLinks := range r.links {
+		for _, other := range userLinks {
+			if other.Long == long {
+				return other, ErrURLConflict
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	copied := *link
+	copied.Long = long
+	copied.UpdatedAt = now
+
+	revision := &entity.ShortlinkRevision{
+		LinkUID:    linkUID,
+		Long:       link.Long,
+		ReplacedAt: now,
+	}
+	err := r.backup.AppendRevised(ctx, &copied, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	r.links[userUID][linkUID] = &copied
+	r.revisions[linkUID] = append(r.revisions[linkUID], revision)
+
+	return &copied, nil
+}
+
+func (r *InMemShortlinkRepo) GetShortlinkRevisions(ctx context.Context, linkUID string) ([]*entity.ShortlinkRevision, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	revisions := slices.Clone(r.revisions[linkUID])
+	slices.Reverse(revisions)
+	return revisions, nil
+}
+
+func (r *InMemShortlinkRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	return r.setDeleted(ctx, userUID, linkUIDs, true)
+}
+
+func (r *InMemShortlinkRepo) UndeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	return r.setDeleted(ctx, userUID, linkUIDs, false)
+}
+
+func (r *InMemShortlinkRepo) GetDeletedShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 
 	var links []*entity.Shortlink
 
 	for _, link := range r.links[userUID] {
-		links = append(links, link)
+		if link.Deleted {
+			links = append(links, link)
+		}
 	}
+	sortByDeletedAt(links)
 	return links, nil
 }
 
-func (r *InMemShortlinkRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) error {
+func (r *InMemShortlinkRepo) PurgeDeletedShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	return r.purge(ctx, func(link *entity.Shortlink) bool {
+		return link.Deleted && link.DeletedAt != nil && link.DeletedAt.Before(before)
+	})
+}
+
+// setDeleted replaces links with updated copies, as the stored ones may already be read outside the lock.
+// Returns UIDs of the updated links
+func (r *InMemShortlinkRepo) setDeleted(ctx context.Context, userUID string, linkUIDs []string, deleted bool) ([]string, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if _, ok := r.links[userUID]; !ok {
-		return nil
+		return nil, nil
 	}
 
-	for uid := range r.links[userUID] {
-		if slices.Contains(linkUIDs, uid) {
-			delete(r.links[userUID], uid)
+	now := time.Now().UTC()
+	var deletedAt *time.Time
+	if deleted {
+		deletedAt = &now
+	}
+
+	var updated []*entity.Shortlink
+	for uid, link := range r.links[userUID] {
+		if link.Deleted != deleted && slices.Contains(linkUIDs, uid) {
+			copied := *link
+			copied.Deleted = deleted
+			copied.DeletedAt = deletedAt
+			copied.UpdatedAt = now
+			updated = append(updated, &copied)
 		}
 	}
-	return nil
+	if len(updated) == 0 {
+		return nil, nil
+	}
+
+	err := r.backup.AppendSaved(ctx, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedUIDs := make([]string, 0, len(updated))
+	for _, link := range updated {
+		r.links[userUID][link.UID] = link
+		updatedUIDs = append(updatedUIDs, link.UID)
+	}
+	return updatedUIDs, nil
+}
+
+func (r *InMemShortlinkRepo) DeleteExpiredShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	return r.purge(ctx, func(link *entity.Shortlink) bool {
+		return link.IsExpired(before)
+	})
+}
+
+// purge hard-deletes links matching the filter
+func (r *InMemShortlinkRepo) purge(ctx context.Context, filter func(link *entity.Shortlink) bool) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var deleted int64
+
+	for userUID, userLinks := range r.links {
+		var matched []string
+		for uid, link := range userLinks {
+			if filter(link) {
+				matched = append(matched, uid)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		err := r.backup.AppendDeleted(ctx, userUID, matched)
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, uid := range matched {
+			delete(userLinks, uid)
+			delete(r.revisions, uid)
+		}
+		deleted += int64(len(matched))
+	}
+	return deleted, nil
 }
 
 func (r *InMemShortlinkRepo) Backup(ctx context.Context) error {
@@ -133,13 +373,24 @@ func (r *InMemShortlinkRepo) Backup(ctx context.Context) error {
 
 	for _, userLinks := range r.links {
 		for _, link := range userLinks {
-			links = append(links, link)
+			links = append(links, r.withRevisions(link))
 		}
 	}
 
 	return r.backup.Backup(ctx, links)
 }
 
+// withRevisions returns a copy of the link with its revisions attached, must be called with the mutex held
+func (r *InMemShortlinkRepo) withRevisions(link *entity.Shortlink) *entity.Shortlink {
+	revisions := r.revisions[link.UID]
+	if len(revisions) == 0 {
+		return link
+	}
+	copied := *link
+	copied.Revisions = slices.Clone(revisions)
+	return &copied
+}
+
 func (r *InMemShortlinkRepo) Restore(ctx context.Context) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -150,6 +401,10 @@ func (r *InMemShortlinkRepo) Restore(ctx context.Context) error {
 	}
 
 	for _, link := range links {
+		if len(link.Revisions) > 0 {
+			r.revisions[link.UID] = link.Revisions
+			link.Revisions = nil
+		}
 		if _, ok := r.links[link.UserUID]; !ok {
 			r.links[link.UserUID] = make(map[string]*entity.Shortlink)
 		}
@@ -159,6 +414,28 @@ func (r *InMemShortlinkRepo) Restore(ctx context.Context) error {
 	return nil
 }
 
+// newShortlinkPage takes links fetched with one extra (if limited), the extra one means there is a next page
+func newShortlinkPage(query entity.ShortlinkQuery, links []*entity.Shortlink) *entity.ShortlinkPage {
+	page := &entity.ShortlinkPage{Links: links}
+
+	if query.Limit > 0 && len(links) > query.Limit {
+		page.Links = links[:query.Limit]
+		page.Next = query.CursorAt(page.Links[query.Limit-1])
+	}
+	return page
+}
+
+// sortByDeletedAt puts the most recently deleted links first
+func sortByDeletedAt(links []*entity.Shortlink) {
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i].DeletedAt, links[j].DeletedAt
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.After(*b)
+	})
+}
+
 func (r *InMemShortlinkRepo) Close(ctx context.Context) error {
 	return r.backup.Close(ctx)
 }
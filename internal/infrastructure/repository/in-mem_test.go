@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+)
+
+func TestInMemShortlinkRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemShortlinkRepo(nil)
+
+	link := &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org"}
+	_, err := repo.SaveShortlink(ctx, link)
+	require.NoError(t, err)
+
+	t.Run("batch conflict saves nothing", func(t *testing.T) {
+		_, err := repo.SaveShortlinks(ctx, []*entity.Shortlink{
+			{UID: "link3", UserUID: "user1", Short: "http://127.0.0.1/link3", Long: "https://example.com/3"},
+			{UID: "link1", UserUID: "user2", Short: "http://127.0.0.1/link1", Long: "https://example.com/1"},
+		})
+		assert.ErrorIs(t, err, ErrUIDConflict)
+
+		_, err = repo.SaveShortlinks(ctx, []*entity.Shortlink{
+			{UID: "link4", UserUID: "user1", Short: "http://127.0.0.1/link4", Long: "https://example.com/4"},
+			{UID: "link4", UserUID: "user1", Short: "http://127.0.0.1/link4", Long: "https://example.com/5"},
+		})
+		assert.ErrorIs(t, err, ErrUIDConflict)
+
+		found, err := repo.FindShortlinks(ctx, []string{"link3", "link4"})
+		require.NoError(t, err)
+		assert.Empty(t, found)
+
+		count, err := repo.CountShortlinks(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("soft delete", func(t *testing.T) {
+		deleted, err := repo.DeleteShortlinks(ctx, "user1", []string{"link1", "unknown"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"link1"}, deleted)
+
+		found, err := repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.True(t, found.Deleted)
+		assert.False(t, found.UpdatedAt.IsZero())
+		// Previously returned link is not changed under the reader
+		assert.False(t, link.Deleted)
+
+		found, err = repo.FindShortlink(ctx, "user1", "link1")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		page, err := repo.GetShortlinks(ctx, "user1", entity.ShortlinkQuery{})
+		require.NoError(t, err)
+		assert.Empty(t, page.Links)
+
+		trash, err := repo.GetDeletedShortlinks(ctx, "user1")
+		require.NoError(t, err)
+		require.Len(t, trash, 1)
+		assert.NotNil(t, trash[0].DeletedAt)
+	})
+
+	t.Run("undelete", func(t *testing.T) {
+		restored, err := repo.UndeleteShortlinks(ctx, "user1", []string{"link1", "unknown"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"link1"}, restored)
+
+		found, err := repo.FindShortlink(ctx, "user1", "link1")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.False(t, found.Deleted)
+		assert.Nil(t, found.DeletedAt)
+	})
+
+	t.Run("import skips conflicts", func(t *testing.T) {
+		imported, err := repo.ImportShortlinks(ctx, []*entity.Shortlink{
+			{UID: "link1", UserUID: "user2", Short: "http://127.0.0.1/link1", Long: "https://example.com"},
+			{UID: "link2", UserUID: "user2", Short: "http://127.0.0.1/link2", Long: "https://example.com", Deleted: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), imported)
+
+		var uids []string
+		err = repo.IterateShortlinks(ctx, func(link *entity.Shortlink) error {
+			uids = append(uids, link.UID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"link1", "link2"}, uids)
+
+		count, err := repo.CountShortlinks(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("retarget", func(t *testing.T) {
+		updated, err := repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.net")
+		require.NoError(t, err)
+		require.NotNil(t, updated)
+		assert.Equal(t, "https://example.net", updated.Long)
+
+		// Same URL is not a new revision
+		_, err = repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.net")
+		require.NoError(t, err)
+
+		existing, err := repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.com")
+		assert.ErrorIs(t, err, ErrURLConflict)
+		require.NotNil(t, existing)
+		assert.Equal(t, "link2", existing.UID)
+
+		updated, err = repo.UpdateShortlinkLong(ctx, "user2", "link1", "https://ya.ru")
+		require.NoError(t, err)
+		assert.Nil(t, updated)
+
+		revisions, err := repo.GetShortlinkRevisions(ctx, "link1")
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "https://example.org", revisions[0].Long)
+	})
+
+	t.Run("purge trash", func(t *testing.T) {
+		_, err := repo.DeleteShortlinks(ctx, "user1", []string{"link1"})
+		require.NoError(t, err)
+
+		purged, err := repo.PurgeDeletedShortlinks(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		found, err := repo.FindShortlink(ctx, "", "link1")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		revisions, err := repo.GetShortlinkRevisions(ctx, "link1")
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+	})
+}
+
+func TestInMemCopyRevisions(t *testing.T) {
+	ctx := context.Background()
+
+	source := NewInMemShortlinkRepo(nil)
+	_, err := source.SaveShortlink(ctx, &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org"})
+	require.NoError(t, err)
+	_, err = source.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.com")
+	require.NoError(t, err)
+	_, err = source.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.net")
+	require.NoError(t, err)
+
+	var links []*entity.Shortlink
+	err = source.IterateShortlinks(ctx, func(link *entity.Shortlink) error {
+		links = append(links, link)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Len(t, links[0].Revisions, 2)
+
+	target := NewInMemShortlinkRepo(nil)
+	imported, err := target.ImportShortlinks(ctx, links)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), imported)
+
+	revisions, err := target.GetShortlinkRevisions(ctx, "link1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "https://example.com", revisions[0].Long)
+	assert.Equal(t, "https://example.org", revisions[1].Long)
+
+	// History is kept aside, not on the link itself
+	found, err := target.FindShortlink(ctx, "user1", "link1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Nil(t, found.Revisions)
+}
+
+func TestInMemListShortlinks(t *testing.T) {
+	testListShortlinks(t, NewInMemShortlinkRepo(nil))
+}
+
+// testListShortlinks checks sorting, filters and paging, which every repo implements on its own
+func testListShortlinks(t *testing.T, repo ShortlinkRepo) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	longs := []string{"https://example.org/a", "https://www.example.org/b", "https://google.com/search?q=Example", "https://notexample.org", "https://ya.ru/c"}
+	for i, long := range longs {
+		uid := fmt.Sprintf("link%d", i)
+		_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: uid, UserUID: "user1", Short: "http://127.0.0.1/" + uid, Long: long, CreatedAt: start.Add(time.Duration(i) * time.Hour)})
+		require.NoError(t, err)
+	}
+	// Links of other users and deleted ones are never listed
+	_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: "other", UserUID: "user2", Short: "http://127.0.0.1/other", Long: "https://example.org/other", CreatedAt: start})
+	require.NoError(t, err)
+	_, err = repo.SaveShortlink(ctx, &entity.Shortlink{UID: "deleted", UserUID: "user1", Short: "http://127.0.0.1/deleted", Long: "https://example.org/deleted", CreatedAt: start})
+	require.NoError(t, err)
+	_, err = repo.DeleteShortlinks(ctx, "user1", []string{"deleted"})
+	require.NoError(t, err)
+
+	createdAfter := start.Add(2 * time.Hour)
+
+	tests := []struct {
+		name  string
+		query entity.ShortlinkQuery
+		want  []string
+	}{
+		{
+			name:  "oldest first by default",
+			query: entity.ShortlinkQuery{},
+			want:  []string{"link0", "link1", "link2", "link3", "link4"},
+		},
+		{
+			name:  "newest first",
+			query: entity.ShortlinkQuery{Desc: true},
+			want:  []string{"link4", "link3", "link2", "link1", "link0"},
+		},
+		{
+			name:  "by long URL",
+			query: entity.ShortlinkQuery{Sort: entity.ShortlinkSortLong},
+			want:  []string{"link0", "link2", "link3", "link1", "link4"},
+		},
+		{
+			name:  "domain with subdomains",
+			query: entity.ShortlinkQuery{Domain: "example.org"},
+			want:  []string{"link0", "link1"},
+		},
+		{
+			name:  "case-insensitive search",
+			query: entity.ShortlinkQuery{Search: "EXAMPLE"},
+			want:  []string{"link0", "link1", "link2", "link3"},
+		},
+		{
+			name:  "created after",
+			query: entity.ShortlinkQuery{CreatedAfter: &createdAfter},
+			want:  []string{"link3", "link4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.GetShortlinks(ctx, "user1", tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, shortlinkUIDs(page.Links))
+			assert.Nil(t, page.Next)
+
+			// Walking the pages gives the same links in the same order
+			query := tt.query
+			query.Limit = 2
+
+			var uids []string
+			for pages := 1; ; pages++ {
+				require.LessOrEqual(t, pages, len(tt.want))
+
+				page, err := repo.GetShortlinks(ctx, "user1", query)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(page.Links), query.Limit)
+				uids = append(uids, shortlinkUIDs(page.Links)...)
+
+				if page.Next == nil {
+					break
+				}
+				query.After = page.Next
+			}
+			assert.Equal(t, tt.want, uids)
+		})
+	}
+}
+
+func shortlinkUIDs(links []*entity.Shortlink) []string {
+	uids := make([]string, 0, len(links))
+	for _, link := range links {
+		uids = append(uids, link.UID)
+	}
+	return uids
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 )
@@ -12,8 +13,31 @@ type ShortlinkRepo interface {
 
 	SaveShortlinks(ctx context.Context, links []*entity.Shortlink) ([]*entity.Shortlink, error)
 	FindShortlinks(ctx context.Context, linkUIDs []string) ([]*entity.Shortlink, error)
-	GetShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error)
-	DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) error
+	// GetShortlinks returns a page of links of the user, deleted ones excluded
+	GetShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (*entity.ShortlinkPage, error)
+	// UpdateShortlinkLong retargets a link of the user, the previous long URL is kept as a revision.
+	// Returns nil if the user has no such link, or the link that has the long URL already with ErrURLConflict
+	UpdateShortlinkLong(ctx context.Context, userUID, linkUID, long string) (*entity.Shortlink, error)
+	// GetShortlinkRevisions returns previous long URLs of the link, most recent first
+	GetShortlinkRevisions(ctx context.Context, linkUID string) ([]*entity.ShortlinkRevision, error)
+	// DeleteShortlinks moves links to trash, returns UIDs of the links that were actually deleted,
+	// i.e. owned by the user and not deleted yet
+	DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error)
+	// UndeleteShortlinks restores links from trash, returns UIDs of the restored links
+	UndeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error)
+	// GetDeletedShortlinks returns links of the user in trash, most recently deleted first
+	GetDeletedShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error)
+	// PurgeDeletedShortlinks hard-deletes links moved to trash before the given time
+	PurgeDeletedShortlinks(ctx context.Context, before time.Time) (int64, error)
+	// DeleteExpiredShortlinks hard-deletes links that expired before the given time
+	DeleteExpiredShortlinks(ctx context.Context, before time.Time) (int64, error)
+
+	// CountShortlinks counts links of all users, deleted ones included, as their UIDs are still taken
+	CountShortlinks(ctx context.Context) (int64, error)
+	// IterateShortlinks calls fn for every link of every user, deleted ones included, with their revisions
+	IterateShortlinks(ctx context.Context, fn func(link *entity.Shortlink) error) error
+	// ImportShortlinks saves links as they are (UIDs, deleted flags, revisions etc.), skipping conflicting ones
+	ImportShortlinks(ctx context.Context, links []*entity.Shortlink) (int64, error)
 
 	Ping(ctx context.Context) error
 
@@ -21,3 +45,41 @@ type ShortlinkRepo interface {
 	Restore(ctx context.Context) error
 	Close(ctx context.Context) error
 }
+
+type (
+	// NotificationSource subscribes to notifications sent by all instances sharing the storage
+	NotificationSource interface {
+		Listen(ctx context.Context, channel string) (NotificationStream, error)
+	}
+	NotificationStream interface {
+		// Next blocks until a notification arrives and returns its payload, the stream is broken after an error
+		Next(ctx context.Context) (string, error)
+		Close(ctx context.Context) error
+	}
+)
+
+type ClickRepo interface {
+	SaveClicks(ctx context.Context, clicks []*entity.Click) error
+	GetClickStats(ctx context.Context, linkUID string, query entity.ClickStatsQuery) (*entity.ClickStats, error)
+}
+
+// DeleteQueueRepo is a durable outbox of deletes: a task is stored before the delete is acknowledged,
+// and stays there until it is done or dead-lettered. Done tasks are kept with their results until purged
+type DeleteQueueRepo interface {
+	// EnqueueDeleteTask stores a new task and sets its ID
+	EnqueueDeleteTask(ctx context.Context, task *entity.DeleteTask) error
+	// GetDeleteTask returns nil if there is no such task (e.g. it has been purged)
+	GetDeleteTask(ctx context.Context, taskID int64) (*entity.DeleteTask, error)
+	// GetDueDeleteTasks returns up to limit pending tasks that are due at now, oldest first
+	GetDueDeleteTasks(ctx context.Context, now time.Time, limit int) ([]*entity.DeleteTask, error)
+	CountPendingDeleteTasks(ctx context.Context) (int64, error)
+	// CompleteDeleteTasks stores Attempts, Deleted, NotFound and DoneAt of the tasks
+	CompleteDeleteTasks(ctx context.Context, tasks []*entity.DeleteTask) error
+	// RetryDeleteTask stores Attempts, NextAttemptAt and LastError of the task
+	RetryDeleteTask(ctx context.Context, task *entity.DeleteTask) error
+	// DeadLetterDeleteTask stores Attempts, LastError and DeadAt of the task, it is never due again
+	DeadLetterDeleteTask(ctx context.Context, task *entity.DeleteTask) error
+	GetDeadDeleteTasks(ctx context.Context) ([]*entity.DeleteTask, error)
+	// PurgeDeleteTasks removes tasks done before the given time
+	PurgeDeleteTasks(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
+)
+
+type (
+	// MetricsShortlinkRepo records latency and errors of every operation of the wrapped repo
+	MetricsShortlinkRepo struct {
+		repo ShortlinkRepo
+	}
+	// MetricsClickRepo records latency and errors of every operation of the wrapped repo
+	MetricsClickRepo struct {
+		repo ClickRepo
+	}
+	// MetricsDeleteQueueRepo records latency and errors of every operation of the wrapped repo
+	MetricsDeleteQueueRepo struct {
+		repo DeleteQueueRepo
+	}
+)
+
+func NewMetricsShortlinkRepo(repo ShortlinkRepo) *MetricsShortlinkRepo {
+	return &MetricsShortlinkRepo{repo: repo}
+}
+
+func NewMetricsClickRepo(repo ClickRepo) *MetricsClickRepo {
+	return &MetricsClickRepo{repo: repo}
+}
+
+func NewMetricsDeleteQueueRepo(repo DeleteQueueRepo) *MetricsDeleteQueueRepo {
+	return &MetricsDeleteQueueRepo{repo: repo}
+}
+
+func (r *MetricsShortlinkRepo) SaveShortlink(ctx context.Context, link *entity.Shortlink) (*entity.Shortlink, error) {
+	start := time.Now()
+	result, err := r.repo.SaveShortlink(ctx, link)
+	observe("save_shortlink", start, err)
+	return result, err
+}
+
+func (r *MetricsShortlinkRepo) FindShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error) {
+	start := time.Now()
+	link, err := r.repo.FindShortlink(ctx, userUID, linkUID)
+	observe("find_shortlink", start, err)
+	return link, err
+}
+
+func (r *MetricsShortlinkRepo) SaveShortlinks(ctx context.Context, links []*entity.Shortlink) ([]*entity.Shortlink, error) {
+	start := time.Now()
+	result, err := r.repo.SaveShortlinks(ctx, links)
+	observe("save_shortlinks", start, err)
+	return result, err
+}
+
+func (r *MetricsShortlinkRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([]*entity.Shortlink, error) {
+	start := time.Now()
+	links, err := r.repo.FindShortlinks(ctx, linkUIDs)
+	observe("find_shortlinks", start, err)
+	return links, err
+}
+
+func (r *MetricsShortlinkRepo) GetShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (*entity.ShortlinkPage, error) {
+	start := time.Now()
+	page, err := r.repo.GetShortlinks(ctx, userUID, query)
+	observe("get_shortlinks", start, err)
+	return page, err
+}
+
+func (r *MetricsShortlinkRepo) UpdateShortlinkLong(ctx context.Context, userUID, linkUID, long string) (*entity.Shortlink, error) {
+	start := time.Now()
+	link, err := r.repo.UpdateShortlinkLong(ctx, userUID, linkUID, long)
+	observe("update_shortlink_long", start, err)
+	return link, err
+}
+
+func (r *MetricsShortlinkRepo) GetShortlinkRevisions(ctx context.Context, linkUID string) ([]*entity.ShortlinkRevision, error) {
+	start := time.Now()
+	revisions, err := r.repo.GetShortlinkRevisions(ctx, linkUID)
+	observe("get_shortlink_revisions", start, err)
+	return revisions, err
+}
+
+func (r *MetricsShortlinkRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	start := time.Now()
+	deleted, err := r.repo.DeleteShortlinks(ctx, userUID, linkUIDs)
+	observe("delete_shortlinks", start, err)
+	return deleted, err
+}
+
+func (r *MetricsShortlinkRepo) UndeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	start := time.Now()
+	restored, err := r.repo.UndeleteShortlinks(ctx, userUID, linkUIDs)
+	observe("undelete_shortlinks", start, err)
+	return restored, err
+}
+
+func (r *MetricsShortlinkRepo) GetDeletedShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error) {
+	start := time.Now()
+	links, err := r.repo.GetDeletedShortlinks(ctx, userUID)
+	observe("get_deleted_shortlinks", start, err)
+	return links, err
+}
+
+func (r *MetricsShortlinkRepo) PurgeDeletedShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	start := time.Now()
+	purged, err := r.repo.PurgeDeletedShortlinks(ctx, before)
+	observe("purge_deleted_shortlinks", start, err)
+	return purged, err
+}
+
+func (r *MetricsShortlinkRepo) DeleteExpiredShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	start := time.Now()
+	deleted, err := r.repo.DeleteExpiredShortlinks(ctx, before)
+	observe("delete_expired_shortlinks", start, err)
+	return deleted, err
+}
+
+func (r *MetricsShortlinkRepo) CountShortlinks(ctx context.Context) (int64, error) {
+	start := time.Now()
+	count, err := r.repo.CountShortlinks(ctx)
+	observe("count_shortlinks", start, err)
+	return count, err
+}
+
+func (r *MetricsShortlinkRepo) IterateShortlinks(ctx context.Context, fn func(link *entity.Shortlink) error) error {
+	start := time.Now()
+	err := r.repo.IterateShortlinks(ctx, fn)
+	observe("iterate_shortlinks", start, err)
+	return err
+}
+
+func (r *MetricsShortlinkRepo) ImportShortlinks(ctx context.Context, links []*entity.Shortlink) (int64, error) {
+	start := time.Now()
+	imported, err := r.repo.ImportShortlinks(ctx, links)
+	observe("import_shortlinks", start, err)
+	return imported, err
+}
+
+func (r *MetricsShortlinkRepo) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := r.repo.Ping(ctx)
+	observe("ping", start, err)
+	return err
+}
+
+func (r *MetricsShortlinkRepo) Backup(ctx context.Context) error {
+	start := time.Now()
+	err := r.repo.Backup(ctx)
+	observe("backup", start, err)
+	return err
+}
+
+func (r *MetricsShortlinkRepo) Restore(ctx context.Context) error {
+	start := time.Now()
+	err := r.repo.Restore(ctx)
+	observe("restore", start, err)
+	return err
+}
+
+func (r *MetricsShortlinkRepo) Close(ctx context.Context) error {
+	return r.repo.Close(ctx)
+}
+
+func (r *MetricsClickRepo) SaveClicks(ctx context.Context, clicks []*entity.Click) error {
+	start := time.Now()
+	err := r.repo.SaveClicks(ctx, clicks)
+	observe("save_clicks", start, err)
+	return err
+}
+
+func (r *MetricsClickRepo) GetClickStats(ctx context.Context, linkUID string, query entity.ClickStatsQuery) (*entity.ClickStats, error) {
+	start := time.Now()
+	stats, err := r.repo.GetClickStats(ctx, linkUID, query)
+	observe("get_click_stats", start, err)
+	return stats, err
+}
+
+func (r *MetricsDeleteQueueRepo) EnqueueDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	start := time.Now()
+	err := r.repo.EnqueueDeleteTask(ctx, task)
+	observe("enqueue_delete_task", start, err)
+	return err
+}
+
+func (r *MetricsDeleteQueueRepo) GetDeleteTask(ctx context.Context, taskID int64) (*entity.DeleteTask, error) {
+	start := time.Now()
+	task, err := r.repo.GetDeleteTask(ctx, taskID)
+	observe("get_delete_task", start, err)
+	return task, err
+}
+
+func (r *MetricsDeleteQueueRepo) GetDueDeleteTasks(ctx context.Context, now time.Time, limit int) ([]*entity.DeleteTask, error) {
+	start := time.Now()
+	tasks, err := r.repo.GetDueDeleteTasks(ctx, now, limit)
+	observe("get_due_delete_tasks", start, err)
+	return tasks, err
+}
+
+func (r *MetricsDeleteQueueRepo) CountPendingDeleteTasks(ctx context.Context) (int64, error) {
+	start := time.Now()
+	pending, err := r.repo.CountPendingDeleteTasks(ctx)
+	observe("count_pending_delete_tasks", start, err)
+	return pending, err
+}
+
+func (r *MetricsDeleteQueueRepo) CompleteDeleteTasks(ctx context.Context, tasks []*entity.DeleteTask) error {
+	start := time.Now()
+	err := r.repo.CompleteDeleteTasks(ctx, tasks)
+	observe("complete_delete_tasks", start, err)
+	return err
+}
+
+func (r *MetricsDeleteQueueRepo) RetryDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	start := time.Now()
+	err := r.repo.RetryDeleteTask(ctx, task)
+	observe("retry_delete_task", start, err)
+	return err
+}
+
+func (r *MetricsDeleteQueueRepo) DeadLetterDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	start := time.Now()
+	err := r.repo.DeadLetterDeleteTask(ctx, task)
+	observe("dead_letter_delete_task", start, err)
+	return err
+}
+
+func (r *MetricsDeleteQueueRepo) GetDeadDeleteTasks(ctx context.Context) ([]*entity.DeleteTask, error) {
+	start := time.Now()
+	tasks, err := r.repo.GetDeadDeleteTasks(ctx)
+	observe("get_dead_delete_tasks", start, err)
+	return tasks, err
+}
+
+func (r *MetricsDeleteQueueRepo) PurgeDeleteTasks(ctx context.Context, before time.Time) (int64, error) {
+	start := time.Now()
+	purged, err := r.repo.PurgeDeleteTasks(ctx, before)
+	observe("purge_delete_tasks", start, err)
+	return purged, err
+}
+
+func observe(operation string, start time.Time, err error) {
+	metrics.RepoOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	// Conflicts are expected outcomes, handled by the callers
+	if err != nil && !errors.Is(err, ErrURLConflict) && !errors.Is(err, ErrUIDConflict) {
+		metrics.RepoOperationErrors.WithLabelValues(operation).Inc()
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresNotifier listens on a dedicated connection, as a pooled one may be closed or reused at any time
+type PostgresNotifier struct {
+	connString string
+}
+
+type postgresNotificationStream struct {
+	conn *pgx.Conn
+}
+
+func NewPostgresNotifier(connString string) *PostgresNotifier {
+	return &PostgresNotifier{connString: connString}
+}
+
+func (n *PostgresNotifier) Listen(ctx context.Context, channel string) (NotificationStream, error) {
+	conn, err := pgx.Connect(ctx, n.connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		_ = conn.Close(ctx)
+		return nil, err
+	}
+
+	return &postgresNotificationStream{conn: conn}, nil
+}
+
+func (s *postgresNotificationStream) Next(ctx context.Context) (string, error) {
+	notification, err := s.conn.WaitForNotification(ctx)
+	if err != nil {
+		return "", err
+	}
+	return notification.Payload, nil
+}
+
+func (s *postgresNotificationStream) Close(ctx context.Context) error {
+	return s.conn.Close(ctx)
+}
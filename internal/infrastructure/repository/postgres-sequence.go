@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// PostgresSequence hands out numbers of the Postgres link UID sequence, taking a range of them at a time,
+// so that only every rangeSize-th link costs a round trip. Numbers left in the range on shutdown are never used
+type PostgresSequence struct {
+	mutex     sync.Mutex
+	repo      *PostgresRepo
+	rangeSize int
+	numbers   []uint64
+}
+
+func NewPostgresSequence(repo *PostgresRepo, rangeSize int) *PostgresSequence {
+	return &PostgresSequence{
+		repo:      repo,
+		rangeSize: max(rangeSize, 1),
+	}
+}
+
+func (s *PostgresSequence) Next(ctx context.Context) (uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.numbers) == 0 {
+		numbers, err := s.repo.NextLinkNumbers(ctx, s.rangeSize)
+		if err != nil {
+			return 0, err
+		}
+		s.numbers = numbers
+	}
+
+	number := s.numbers[0]
+	s.numbers = s.numbers[1:]
+	return number, nil
+}
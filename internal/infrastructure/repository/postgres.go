@@ -3,28 +3,82 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/storage"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/tracing"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
 )
 
+const (
+	shortlinkColumns  = "link_uid, user_uid, short, long, deleted, correlation_id, created_at, updated_at, expires_at, deleted_at"
+	deleteTaskColumns = "id, user_uid, link_uids, attempts, next_attempt_at, last_error, deleted_count, not_found, created_at, done_at, dead_at"
+
+	// Each restored row takes 10 parameters, Postgres allows up to 65535 per query
+	restoreBatchSize = 1000
+
+	// urlHostExpr extracts the host of the long URL in lower case, for the domain filter
+	urlHostExpr = "lower(substring(long from '^[^:/?#]+://(?:[^/?#@]*@)?([^/?#:]+)'))"
+
+	uniqueViolationCode = "23505"
+	linkUIDUniqueKey    = "shortlinks_link_uid_key"
+)
+
 var (
 	saveShortlinkStmt       *sql.Stmt
 	findShortlinkStmt       *sql.Stmt
 	findShortlinksStmt      *sql.Stmt
 	findShortlinkByUserStmt *sql.Stmt
-	getShortlinksByUserStmt *sql.Stmt
+
+	lockShortlinkByUserStmt      *sql.Stmt
+	findShortlinkByLongStmt      *sql.Stmt
+	updateShortlinkLongStmt      *sql.Stmt
+	saveRevisionStmt             *sql.Stmt
+	getRevisionsStmt             *sql.Stmt
+	getRevisionsOfShortlinksStmt *sql.Stmt
+	nextLinkNumbersStmt          *sql.Stmt
+	advanceLinkUIDSeqStmt        *sql.Stmt
+	notifyStmt                   *sql.Stmt
+	deleteShortlinksStmt         *sql.Stmt
+	undeleteShortlinksStmt       *sql.Stmt
+	getDeletedShortlinksStmt     *sql.Stmt
+	purgeDeletedShortlinksStmt   *sql.Stmt
+	deleteExpiredShortlinksStmt  *sql.Stmt
+
+	countShortlinksStmt  *sql.Stmt
+	getAllShortlinksStmt *sql.Stmt
+
+	saveClickStmt             *sql.Stmt
+	countClicksStmt           *sql.Stmt
+	getClickBucketsStmt       *sql.Stmt
+	getTopClickReferrersStmt  *sql.Stmt
+	getTopClickUserAgentsStmt *sql.Stmt
+
+	enqueueDeleteTaskStmt       *sql.Stmt
+	getDeleteTaskStmt           *sql.Stmt
+	getDueDeleteTasksStmt       *sql.Stmt
+	countPendingDeleteTasksStmt *sql.Stmt
+	completeDeleteTaskStmt      *sql.Stmt
+	retryDeleteTaskStmt         *sql.Stmt
+	deadLetterDeleteTaskStmt    *sql.Stmt
+	getDeadDeleteTasksStmt      *sql.Stmt
+	purgeDeleteTasksStmt        *sql.Stmt
 )
 
 type PostgresRepo struct {
@@ -77,24 +131,32 @@ func NewPostgresRepo(ctx context.Context, cfg config.PostgreSQL, backup storage.
 	return r, nil
 }
 
-func (r *PostgresRepo) Ping(ctx context.Context) error {
+func (r *PostgresRepo) Ping(ctx context.Context) (err error) {
+	ctx, span := startQuerySpan(ctx, "Ping", "", "")
+	defer func() { tracing.End(span, err) }()
+
 	cancelCtx, cancel := context.WithTimeout(ctx, r.cfg.PingTimeout)
 	defer cancel()
 
-	err := r.db.PingContext(cancelCtx)
+	err = r.db.PingContext(cancelCtx)
 	if err != nil {
 		return r.log.Wrap(err, "ping")
 	}
 	return nil
 }
 
-func (r *PostgresRepo) SaveShortlink(ctx context.Context, link *entity.Shortlink) (*entity.Shortlink, error) {
-	result := new(entity.Shortlink)
+func (r *PostgresRepo) SaveShortlink(ctx context.Context, link *entity.Shortlink) (_ *entity.Shortlink, err error) {
+	ctx, span := startQuerySpan(ctx, "SaveShortlink", "INSERT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
 	var conflict bool
 
-	row := saveShortlinkStmt.QueryRowContext(ctx, link.UID, link.UserUID, link.Short, link.Long, link.CorrelationID)
-	err := row.Scan(&result.UID, &result.UserUID, &result.Short, &result.Long, &result.CorrelationID, &conflict)
+	row := saveShortlinkStmt.QueryRowContext(ctx, link.UID, link.UserUID, link.Short, link.Long, link.CorrelationID, link.CreatedAt, link.UpdatedAt, link.ExpiresAt)
+	result, err := scanShortlink(row, &conflict)
 	if err != nil {
+		if isUIDConflict(err) {
+			return nil, ErrUIDConflict
+		}
 		return nil, r.log.Wrap(err, "insert")
 	}
 	if conflict {
@@ -103,7 +165,10 @@ func (r *PostgresRepo) SaveShortlink(ctx context.Context, link *entity.Shortlink
 	return result, nil
 }
 
-func (r *PostgresRepo) SaveShortlinks(ctx context.Context, links []*entity.Shortlink) ([]*entity.Shortlink, error) {
+func (r *PostgresRepo) SaveShortlinks(ctx context.Context, links []*entity.Shortlink) (_ []*entity.Shortlink, err error) {
+	ctx, span := startQuerySpan(ctx, "SaveShortlinks", "INSERT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, r.log.Wrap(err, "begin tx")
@@ -112,17 +177,19 @@ func (r *PostgresRepo) SaveShortlinks(ctx context.Context, links []*entity.Short
 	var result []*entity.Shortlink
 
 	for _, link := range links {
-		resultLink := new(entity.Shortlink)
 		var conflict bool
 
-		row := tx.StmtContext(ctx, saveShortlinkStmt).QueryRowContext(ctx, link.UID, link.UserUID, link.Short, link.Long, link.CorrelationID)
-		err := row.Scan(&resultLink.UID, &resultLink.UserUID, &resultLink.Short, &resultLink.Long, &resultLink.CorrelationID, &conflict)
+		row := tx.StmtContext(ctx, saveShortlinkStmt).QueryRowContext(ctx, link.UID, link.UserUID, link.Short, link.Long, link.CorrelationID, link.CreatedAt, link.UpdatedAt, link.ExpiresAt)
+		resultLink, err := scanShortlink(row, &conflict)
 
 		if err != nil {
 			rollbackErr := tx.Rollback()
 			if rollbackErr != nil {
 				log.Printf("rollback after insert: %s", err)
 			}
+			if isUIDConflict(err) {
+				return nil, ErrUIDConflict
+			}
 			return nil, r.log.Wrap(err, "insert")
 		}
 
@@ -137,7 +204,10 @@ func (r *PostgresRepo) SaveShortlinks(ctx context.Context, links []*entity.Short
 	return result, nil
 }
 
-func (r *PostgresRepo) FindShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error) {
+func (r *PostgresRepo) FindShortlink(ctx context.Context, userUID, linkUID string) (_ *entity.Shortlink, err error) {
+	ctx, span := startQuerySpan(ctx, "FindShortlink", "SELECT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
 	var row *sql.Row
 
 	if userUID != "" {
@@ -146,10 +216,7 @@ func (r *PostgresRepo) FindShortlink(ctx context.Context, userUID, linkUID strin
 		row = findShortlinkStmt.QueryRowContext(ctx, linkUID)
 	}
 
-	link := new(entity.Shortlink)
-	var corrID sql.NullString
-
-	err := row.Scan(&link.UID, &link.UserUID, &link.Short, &link.Long, &link.Deleted, &corrID)
+	link, err := scanShortlink(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -157,15 +224,16 @@ func (r *PostgresRepo) FindShortlink(ctx context.Context, userUID, linkUID strin
 		return nil, r.log.Wrap(err, "scan")
 	}
 
-	link.CorrelationID = corrID.String
-
 	return link, nil
 }
 
-func (r *PostgresRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([]*entity.Shortlink, error) {
+func (r *PostgresRepo) FindShortlinks(ctx context.Context, linkUIDs []string) (_ []*entity.Shortlink, err error) {
+	ctx, span := startQuerySpan(ctx, "FindShortlinks", "SELECT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
 	var links []*entity.Shortlink
 
-	rows, err := findShortlinksStmt.QueryContext(ctx, strings.Join(linkUIDs, ","))
+	rows, err := findShortlinksStmt.QueryContext(ctx, linkUIDs)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return links, nil
@@ -175,13 +243,10 @@ func (r *PostgresRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([
 	defer rows.Close()
 
 	for rows.Next() {
-		link := new(entity.Shortlink)
-		var corrID sql.NullString
-		err := rows.Scan(&link.UID, &link.UserUID, &link.Short, &link.Long, &link.Deleted, &corrID)
+		link, err := scanShortlink(rows)
 		if err != nil {
 			return nil, r.log.Wrap(err, "scan")
 		}
-		link.CorrelationID = corrID.String
 		links = append(links, link)
 	}
 
@@ -193,26 +258,57 @@ func (r *PostgresRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([
 	return links, nil
 }
 
-func (r *PostgresRepo) GetShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error) {
+func (r *PostgresRepo) GetShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (_ *entity.ShortlinkPage, err error) {
+	ctx, span := startQuerySpan(ctx, "GetShortlinks", "SELECT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"user_uid = " + arg(userUID), "deleted = false"}
+
+	if query.Domain != "" {
+		domain := arg(strings.ToLower(query.Domain))
+		conditions = append(conditions, "("+urlHostExpr+" = "+domain+" OR right("+urlHostExpr+", char_length("+domain+") + 1) = '.' || "+domain+")")
+	}
+	if query.Search != "" {
+		conditions = append(conditions, "strpos(lower(long), "+arg(strings.ToLower(query.Search))+") > 0")
+	}
+	if query.CreatedAfter != nil {
+		conditions = append(conditions, "created_at > "+arg(*query.CreatedAfter))
+	}
+
+	column, direction, op := shortlinkOrder(query)
+	if query.After != nil {
+		var after any = query.After.CreatedAt
+		if query.Sort == entity.ShortlinkSortLong {
+			after = query.After.Long
+		}
+		conditions = append(conditions, "("+column+", link_uid) "+op+" ("+arg(after)+", "+arg(query.After.UID)+")")
+	}
+
+	selectQuery := "SELECT " + shortlinkColumns + " FROM shortlinks WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + column + " " + direction + ", link_uid " + direction
+	if query.Limit > 0 {
+		selectQuery += " LIMIT " + arg(query.Limit+1)
+	}
+
 	var links []*entity.Shortlink
 
-	rows, err := getShortlinksByUserStmt.QueryContext(ctx, userUID)
+	rows, err := r.db.QueryContext(ctx, selectQuery, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return links, nil
-		}
 		return nil, r.log.Wrap(err, "select")
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		link := new(entity.Shortlink)
-		var corrID sql.NullString
-		err := rows.Scan(&link.UID, &link.UserUID, &link.Short, &link.Long, &corrID)
+		link, err := scanShortlink(rows)
 		if err != nil {
 			return nil, r.log.Wrap(err, "scan")
 		}
-		link.CorrelationID = corrID.String
 		links = append(links, link)
 	}
 
@@ -221,104 +317,1206 @@ func (r *PostgresRepo) GetShortlinks(ctx context.Context, userUID string) ([]*en
 		return nil, r.log.Wrap(err, "rows next")
 	}
 
-	return links, nil
+	return newShortlinkPage(query, links), nil
 }
 
-func (r *PostgresRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) error {
-	query := "UPDATE shortlinks SET deleted = true WHERE user_uid = $1 AND link_uid IN ("
-	args := make([]any, len(linkUIDs)+1)
-	args[0] = userUID
+func (r *PostgresRepo) UpdateShortlinkLong(ctx context.Context, userUID, linkUID, long string) (_ *entity.Shortlink, err error) {
+	ctx, span := startQuerySpan(ctx, "UpdateShortlinkLong", "UPDATE", "shortlinks")
+	defer func() { tracing.End(span, err) }()
 
-	for i := 0; i < len(linkUIDs); i++ {
-		if i > 0 {
-			query += ", "
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, r.log.Wrap(err, "begin tx")
+	}
+
+	rollback := func() {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			r.log.Error(ctx, rollbackErr).Msg("rollback after update")
+		}
+	}
+
+	// Locked, so that concurrent updates record every replaced URL
+	link, err := scanShortlink(tx.StmtContext(ctx, lockShortlinkByUserStmt).QueryRowContext(ctx, linkUID, userUID))
+	if err != nil {
+		rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
 		}
-		query += fmt.Sprintf("$%d", i+2)
-		args[i+1] = linkUIDs[i]
+		return nil, r.log.Wrap(err, "select for update")
 	}
-	query += ")"
+	if link.Long == long {
+		rollback()
+		return link, nil
+	}
+
+	now := time.Now().UTC()
 
-	_, err := r.db.ExecContext(ctx, query, args...)
+	updated, err := scanShortlink(tx.StmtContext(ctx, updateShortlinkLongStmt).QueryRowContext(ctx, linkUID, long, now))
+	if err != nil {
+		rollback()
+		if isURLConflict(err) {
+			return r.findShortlinkByLong(ctx, long)
+		}
+		return nil, r.log.Wrap(err, "update")
+	}
 
+	_, err = tx.StmtContext(ctx, saveRevisionStmt).ExecContext(ctx, linkUID, link.Long, now)
 	if err != nil {
-		return r.log.Wrap(err, "update shortlinks deleted flag")
+		rollback()
+		return nil, r.log.Wrap(err, "insert revision")
 	}
-	return nil
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, r.log.Wrap(err, "commit after update")
+	}
+
+	r.notifyChanged(ctx, []string{linkUID})
+	return updated, nil
 }
 
-func (r *PostgresRepo) Backup(ctx context.Context) error {
-	return nil
+// findShortlinkByLong returns the link that has the long URL with ErrURLConflict
+func (r *PostgresRepo) findShortlinkByLong(ctx context.Context, long string) (*entity.Shortlink, error) {
+	existing, err := scanShortlink(findShortlinkByLongStmt.QueryRowContext(ctx, long))
+	if err != nil {
+		return nil, r.log.Wrap(err, "select existing")
+	}
+	return existing, ErrURLConflict
 }
 
-func (r *PostgresRepo) Restore(ctx context.Context) error {
-	return nil
+func (r *PostgresRepo) GetShortlinkRevisions(ctx context.Context, linkUID string) (_ []*entity.ShortlinkRevision, err error) {
+	ctx, span := startQuerySpan(ctx, "GetShortlinkRevisions", "SELECT", "shortlink_revisions")
+	defer func() { tracing.End(span, err) }()
+
+	var revisions []*entity.ShortlinkRevision
+
+	rows, err := getRevisionsStmt.QueryContext(ctx, linkUID)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		revision := new(entity.ShortlinkRevision)
+		err := rows.Scan(&revision.LinkUID, &revision.Long, &revision.ReplacedAt)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		revisions = append(revisions, revision)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+
+	return revisions, nil
 }
 
-func (r *PostgresRepo) Close(ctx context.Context) error {
-	err := r.backup.Close(ctx)
+// NextLinkNumbers takes count numbers from the link UID sequence, shared by all instances.
+// Restore and import move the sequence past the count of links, as the in-mem sequence starts after it
+func (r *PostgresRepo) NextLinkNumbers(ctx context.Context, count int) (_ []uint64, err error) {
+	ctx, span := startQuerySpan(ctx, "NextLinkNumbers", "SELECT", "link_uid_seq")
+	defer func() { tracing.End(span, err) }()
+
+	numbers := make([]uint64, 0, count)
+
+	rows, err := nextLinkNumbersStmt.QueryContext(ctx, count)
 	if err != nil {
-		return r.log.Wrap(err, "close backup")
+		return nil, r.log.Wrap(err, "select")
 	}
+	defer rows.Close()
 
-	err = r.closeStatements(ctx)
+	for rows.Next() {
+		var number int64
+		err := rows.Scan(&number)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		numbers = append(numbers, uint64(number))
+	}
+
+	err = rows.Err()
 	if err != nil {
-		return r.log.Wrap(err, "close statements")
+		return nil, r.log.Wrap(err, "rows next")
 	}
 
-	err = r.db.Close()
+	return numbers, nil
+}
+
+func (r *PostgresRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) (_ []string, err error) {
+	ctx, span := startQuerySpan(ctx, "DeleteShortlinks", "UPDATE", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := deleteShortlinksStmt.QueryContext(ctx, userUID, time.Now().UTC(), linkUIDs)
 	if err != nil {
-		return r.log.Wrap(err, "close db")
+		return nil, r.log.Wrap(err, "update shortlinks deleted flag")
 	}
+	defer rows.Close()
 
-	return nil
+	var deleted []string
+	for rows.Next() {
+		var linkUID string
+		err = rows.Scan(&linkUID)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		deleted = append(deleted, linkUID)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+
+	r.notifyChanged(ctx, deleted)
+	return deleted, nil
 }
 
-func (r *PostgresRepo) initStatements(ctx context.Context) error {
-	var err error
+func (r *PostgresRepo) UndeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) (_ []string, err error) {
+	ctx, span := startQuerySpan(ctx, "UndeleteShortlinks", "UPDATE", "shortlinks")
+	defer func() { tracing.End(span, err) }()
 
-	saveShortlinkStmt, err = r.db.PrepareContext(ctx,
-		"WITH inserted AS (INSERT INTO shortlinks(link_uid, user_uid, short, long, correlation_id) VALUES($1, $2, $3, $4, $5)"+
-			" ON CONFLICT (long) DO NOTHING RETURNING link_uid, user_uid, short, long, correlation_id)"+
-			", existing AS (SELECT link_uid, user_uid, short, long, correlation_id FROM shortlinks WHERE long = $4)"+
-			" SELECT *, true AS conflict FROM existing UNION SELECT *, false AS conflict FROM inserted")
+	rows, err := undeleteShortlinksStmt.QueryContext(ctx, userUID, linkUIDs, time.Now().UTC())
 	if err != nil {
-		return r.log.Wrap(err, "prepare saveShortlinkStmt")
+		return nil, r.log.Wrap(err, "update shortlinks deleted flag")
+	}
+	defer rows.Close()
+
+	var restored []string
+	for rows.Next() {
+		var linkUID string
+		err = rows.Scan(&linkUID)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		restored = append(restored, linkUID)
 	}
-	findShortlinkStmt, err = r.db.PrepareContext(ctx, "SELECT link_uid, user_uid, short, long, deleted, correlation_id FROM shortlinks WHERE link_uid = $1")
+
+	err = rows.Err()
 	if err != nil {
-		return r.log.Wrap(err, "prepare findShortlinkStmt")
+		return nil, r.log.Wrap(err, "rows next")
 	}
-	findShortlinksStmt, err = r.db.PrepareContext(ctx, "SELECT link_uid, user_uid, short, long, deleted, correlation_id FROM shortlinks WHERE link_uid IN ($1)")
+
+	r.notifyChanged(ctx, restored)
+	return restored, nil
+}
+
+func (r *PostgresRepo) GetDeletedShortlinks(ctx context.Context, userUID string) (_ []*entity.Shortlink, err error) {
+	ctx, span := startQuerySpan(ctx, "GetDeletedShortlinks", "SELECT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := getDeletedShortlinksStmt.QueryContext(ctx, userUID)
 	if err != nil {
-		return r.log.Wrap(err, "prepare findShortlinksStmt")
+		return nil, r.log.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	var links []*entity.Shortlink
+	for rows.Next() {
+		link, err := scanShortlink(rows)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		links = append(links, link)
 	}
-	findShortlinkByUserStmt, err = r.db.PrepareContext(ctx, "SELECT link_uid, user_uid, short, long, correlation_id FROM shortlinks WHERE link_uid = $1 AND user_uid = $2 AND deleted = false")
+
+	err = rows.Err()
 	if err != nil {
-		return r.log.Wrap(err, "prepare findShortlinkByUserStmt")
+		return nil, r.log.Wrap(err, "rows next")
+	}
+	return links, nil
+}
+
+func (r *PostgresRepo) PurgeDeletedShortlinks(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startQuerySpan(ctx, "PurgeDeletedShortlinks", "DELETE", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := purgeDeletedShortlinksStmt.ExecContext(ctx, before)
+	if err != nil {
+		return 0, r.log.Wrap(err, "delete trashed shortlinks")
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows affected")
+	}
+
+	if purged > 0 {
+		r.notifyAllChanged(ctx)
+	}
+	return purged, nil
+}
+
+func (r *PostgresRepo) DeleteExpiredShortlinks(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startQuerySpan(ctx, "DeleteExpiredShortlinks", "DELETE", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := deleteExpiredShortlinksStmt.ExecContext(ctx, before)
+	if err != nil {
+		return 0, r.log.Wrap(err, "delete expired shortlinks")
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows affected")
+	}
+
+	if deleted > 0 {
+		r.notifyAllChanged(ctx)
+	}
+	return deleted, nil
+}
+
+// notifyChanged tells all instances to evict the links from their caches.
+// A failure is only logged, the caches catch up once their entries expire
+func (r *PostgresRepo) notifyChanged(ctx context.Context, linkUIDs []string) {
+	for _, payload := range changesPayloads(linkUIDs) {
+		_, err := notifyStmt.ExecContext(ctx, ShortlinkChangesChannel, payload)
+		if err != nil {
+			r.log.Error(ctx, err).Msg("notify shortlink changes")
+			return
+		}
+	}
+}
+
+// notifyAllChanged tells all instances to clear their caches, when the changed links are not known
+func (r *PostgresRepo) notifyAllChanged(ctx context.Context) {
+	_, err := notifyStmt.ExecContext(ctx, ShortlinkChangesChannel, allLinksChanged)
+	if err != nil {
+		r.log.Error(ctx, err).Msg("notify shortlink changes")
+	}
+}
+
+func (r *PostgresRepo) SaveClicks(ctx context.Context, clicks []*entity.Click) (err error) {
+	ctx, span := startQuerySpan(ctx, "SaveClicks", "INSERT", "clicks")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.log.Wrap(err, "begin tx")
+	}
+
+	stmt := tx.StmtContext(ctx, saveClickStmt)
+
+	for _, click := range clicks {
+		_, err := stmt.ExecContext(ctx, click.LinkUID, click.Timestamp, click.Referrer, click.UserAgent, click.IP)
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				r.log.Error(ctx, rollbackErr).Msg("rollback after insert")
+			}
+			return r.log.Wrap(err, "insert")
+		}
 	}
-	getShortlinksByUserStmt, err = r.db.PrepareContext(ctx, "SELECT link_uid, user_uid, short, long, correlation_id FROM shortlinks WHERE user_uid = $1 AND deleted = false")
+
+	err = tx.Commit()
 	if err != nil {
-		return r.log.Wrap(err, "prepare getShortlinksByUserStmt")
+		return r.log.Wrap(err, "commit after insert")
 	}
 
 	return nil
 }
 
-func (r *PostgresRepo) closeStatements(ctx context.Context) error {
-	if err := saveShortlinkStmt.Close(); err != nil {
-		return r.log.Wrap(err, "close saveShortlinkStmt")
+func (r *PostgresRepo) GetClickStats(ctx context.Context, linkUID string, query entity.ClickStatsQuery) (_ *entity.ClickStats, err error) {
+	ctx, span := startQuerySpan(ctx, "GetClickStats", "SELECT", "clicks")
+	defer func() { tracing.End(span, err) }()
+
+	stats := new(entity.ClickStats)
+
+	row := countClicksStmt.QueryRowContext(ctx, linkUID, query.From, query.To)
+	err = row.Scan(&stats.Total, &stats.UniqueVisitors)
+	if err != nil {
+		return nil, r.log.Wrap(err, "count clicks")
 	}
-	if err := findShortlinkStmt.Close(); err != nil {
-		return r.log.Wrap(err, "close findShortlinkStmt")
+
+	rows, err := getClickBucketsStmt.QueryContext(ctx, linkUID, query.From, query.To, query.Bucket)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select click buckets")
 	}
-	if err := findShortlinksStmt.Close(); err != nil {
-		return r.log.Wrap(err, "close findShortlinksStmt")
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket entity.ClickBucket
+		err := rows.Scan(&bucket.Start, &bucket.Count)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan click bucket")
+		}
+		bucket.Start = bucket.Start.UTC()
+		stats.Buckets = append(stats.Buckets, bucket)
 	}
-	if err := findShortlinkByUserStmt.Close(); err != nil {
-		return r.log.Wrap(err, "close findShortlinkByUserStmt")
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+
+	stats.TopReferrers, err = r.getTopClickCounters(ctx, getTopClickReferrersStmt, linkUID, query)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select top referrers")
 	}
-	if err := getShortlinksByUserStmt.Close(); err != nil {
-		return r.log.Wrap(err, "close getShortlinksByUserStmt")
+	stats.TopUserAgents, err = r.getTopClickCounters(ctx, getTopClickUserAgentsStmt, linkUID, query)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select top user agents")
+	}
+
+	return stats, nil
+}
+
+func (r *PostgresRepo) getTopClickCounters(ctx context.Context, stmt *sql.Stmt, linkUID string, query entity.ClickStatsQuery) ([]entity.ClickCounter, error) {
+	var counters []entity.ClickCounter
+
+	rows, err := stmt.QueryContext(ctx, linkUID, query.From, query.To, query.Top)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var counter entity.ClickCounter
+		err := rows.Scan(&counter.Value, &counter.Count)
+		if err != nil {
+			return nil, err
+		}
+		counters = append(counters, counter)
+	}
+
+	return counters, rows.Err()
+}
+
+func (r *PostgresRepo) EnqueueDeleteTask(ctx context.Context, task *entity.DeleteTask) (err error) {
+	ctx, span := startQuerySpan(ctx, "EnqueueDeleteTask", "INSERT", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	linkUIDs, err := json.Marshal(task.LinkUIDs)
+	if err != nil {
+		return r.log.Wrap(err, "marshal link uids")
+	}
+
+	row := enqueueDeleteTaskStmt.QueryRowContext(ctx, task.UserUID, linkUIDs, task.Attempts, task.NextAttemptAt, task.CreatedAt)
+	err = row.Scan(&task.ID)
+	if err != nil {
+		return r.log.Wrap(err, "insert delete task")
 	}
 	return nil
 }
+
+func (r *PostgresRepo) GetDeleteTask(ctx context.Context, taskID int64) (_ *entity.DeleteTask, err error) {
+	ctx, span := startQuerySpan(ctx, "GetDeleteTask", "SELECT", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	tasks, err := r.queryDeleteTasks(ctx, getDeleteTaskStmt, taskID)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return tasks[0], nil
+}
+
+// GetDueDeleteTasks does not lock the tasks: with several instances a task may be run twice, which is harmless
+// as deletes are idempotent
+func (r *PostgresRepo) GetDueDeleteTasks(ctx context.Context, now time.Time, limit int) (_ []*entity.DeleteTask, err error) {
+	ctx, span := startQuerySpan(ctx, "GetDueDeleteTasks", "SELECT", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	return r.queryDeleteTasks(ctx, getDueDeleteTasksStmt, now, limit)
+}
+
+func (r *PostgresRepo) CountPendingDeleteTasks(ctx context.Context) (_ int64, err error) {
+	ctx, span := startQuerySpan(ctx, "CountPendingDeleteTasks", "SELECT", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	var pending int64
+	err = countPendingDeleteTasksStmt.QueryRowContext(ctx).Scan(&pending)
+	if err != nil {
+		return 0, r.log.Wrap(err, "count delete tasks")
+	}
+	return pending, nil
+}
+
+func (r *PostgresRepo) CompleteDeleteTasks(ctx context.Context, tasks []*entity.DeleteTask) (err error) {
+	ctx, span := startQuerySpan(ctx, "CompleteDeleteTasks", "UPDATE", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	notFound := make([][]byte, len(tasks))
+	for i, task := range tasks {
+		notFound[i], err = marshalUIDs(task.NotFound)
+		if err != nil {
+			return r.log.Wrap(err, "marshal not found uids")
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.log.Wrap(err, "begin tx")
+	}
+
+	stmt := tx.StmtContext(ctx, completeDeleteTaskStmt)
+
+	for i, task := range tasks {
+		_, err := stmt.ExecContext(ctx, task.ID, task.Attempts, task.Deleted, notFound[i], task.DoneAt)
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				r.log.Error(ctx, rollbackErr).Msg("rollback after update")
+			}
+			return r.log.Wrap(err, "update delete task")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return r.log.Wrap(err, "commit after update")
+	}
+	return nil
+}
+
+func (r *PostgresRepo) RetryDeleteTask(ctx context.Context, task *entity.DeleteTask) (err error) {
+	ctx, span := startQuerySpan(ctx, "RetryDeleteTask", "UPDATE", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = retryDeleteTaskStmt.ExecContext(ctx, task.ID, task.Attempts, task.NextAttemptAt, task.LastError)
+	if err != nil {
+		return r.log.Wrap(err, "update delete task")
+	}
+	return nil
+}
+
+func (r *PostgresRepo) DeadLetterDeleteTask(ctx context.Context, task *entity.DeleteTask) (err error) {
+	ctx, span := startQuerySpan(ctx, "DeadLetterDeleteTask", "UPDATE", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = deadLetterDeleteTaskStmt.ExecContext(ctx, task.ID, task.Attempts, task.LastError, task.DeadAt)
+	if err != nil {
+		return r.log.Wrap(err, "update delete task")
+	}
+	return nil
+}
+
+func (r *PostgresRepo) GetDeadDeleteTasks(ctx context.Context) (_ []*entity.DeleteTask, err error) {
+	ctx, span := startQuerySpan(ctx, "GetDeadDeleteTasks", "SELECT", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	return r.queryDeleteTasks(ctx, getDeadDeleteTasksStmt)
+}
+
+func (r *PostgresRepo) PurgeDeleteTasks(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startQuerySpan(ctx, "PurgeDeleteTasks", "DELETE", "delete_outbox")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := purgeDeleteTasksStmt.ExecContext(ctx, before)
+	if err != nil {
+		return 0, r.log.Wrap(err, "delete done tasks")
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows affected")
+	}
+	return purged, nil
+}
+
+func (r *PostgresRepo) queryDeleteTasks(ctx context.Context, stmt *sql.Stmt, args ...any) ([]*entity.DeleteTask, error) {
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select delete tasks")
+	}
+	defer rows.Close()
+
+	var tasks []*entity.DeleteTask
+	for rows.Next() {
+		task := new(entity.DeleteTask)
+		var linkUIDs, notFound []byte
+		var doneAt, deadAt sql.NullTime
+
+		err := rows.Scan(&task.ID, &task.UserUID, &linkUIDs, &task.Attempts, &task.NextAttemptAt, &task.LastError,
+			&task.Deleted, &notFound, &task.CreatedAt, &doneAt, &deadAt)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan delete task")
+		}
+		err = json.Unmarshal(linkUIDs, &task.LinkUIDs)
+		if err != nil {
+			return nil, r.log.Wrap(err, "unmarshal link uids")
+		}
+		err = json.Unmarshal(notFound, &task.NotFound)
+		if err != nil {
+			return nil, r.log.Wrap(err, "unmarshal not found uids")
+		}
+		if len(task.NotFound) == 0 {
+			task.NotFound = nil
+		}
+		if doneAt.Valid {
+			task.DoneAt = &doneAt.Time
+		}
+		if deadAt.Valid {
+			task.DeadAt = &deadAt.Time
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+	return tasks, nil
+}
+
+func (r *PostgresRepo) CountShortlinks(ctx context.Context) (count int64, err error) {
+	ctx, span := startQuerySpan(ctx, "CountShortlinks", "SELECT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	err = countShortlinksStmt.QueryRowContext(ctx).Scan(&count)
+	if err != nil {
+		return 0, r.log.Wrap(err, "count shortlinks")
+	}
+	return count, nil
+}
+
+// IterateShortlinks reads links page by page in a single read-only snapshot, with their revisions
+func (r *PostgresRepo) IterateShortlinks(ctx context.Context, fn func(link *entity.Shortlink) error) (err error) {
+	ctx, span := startQuerySpan(ctx, "IterateShortlinks", "SELECT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return r.log.Wrap(err, "begin tx")
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.log.Error(ctx, rollbackErr).Msg("rollback")
+		}
+	}()
+
+	var afterID int64
+	for {
+		links, lastID, err := r.getShortlinksPage(ctx, tx, afterID)
+		if err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		afterID = lastID
+
+		for _, link := range links {
+			err = fn(link)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// getShortlinksPage returns up to restoreBatchSize links with IDs above afterID along with their revisions,
+// and the ID of the last one
+func (r *PostgresRepo) getShortlinksPage(ctx context.Context, tx *sql.Tx, afterID int64) ([]*entity.Shortlink, int64, error) {
+	rows, err := tx.StmtContext(ctx, getAllShortlinksStmt).QueryContext(ctx, afterID, restoreBatchSize)
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	var links []*entity.Shortlink
+	var lastID int64
+	byUID := make(map[string]*entity.Shortlink)
+
+	for rows.Next() {
+		link, err := scanShortlink(rows, &lastID)
+		if err != nil {
+			return nil, 0, r.log.Wrap(err, "scan")
+		}
+		links = append(links, link)
+		byUID[link.UID] = link
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "rows next")
+	}
+	if len(links) == 0 {
+		return nil, 0, nil
+	}
+
+	linkUIDs := make([]string, len(links))
+	for i, link := range links {
+		linkUIDs[i] = link.UID
+	}
+
+	revisionRows, err := tx.StmtContext(ctx, getRevisionsOfShortlinksStmt).QueryContext(ctx, linkUIDs)
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "select revisions")
+	}
+	defer revisionRows.Close()
+
+	for revisionRows.Next() {
+		revision := new(entity.ShortlinkRevision)
+		err := revisionRows.Scan(&revision.LinkUID, &revision.Long, &revision.ReplacedAt)
+		if err != nil {
+			return nil, 0, r.log.Wrap(err, "scan revision")
+		}
+		link := byUID[revision.LinkUID]
+		link.Revisions = append(link.Revisions, revision)
+	}
+	err = revisionRows.Err()
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "revision rows next")
+	}
+
+	return links, lastID, nil
+}
+
+func (r *PostgresRepo) ImportShortlinks(ctx context.Context, links []*entity.Shortlink) (_ int64, err error) {
+	ctx, span := startQuerySpan(ctx, "ImportShortlinks", "INSERT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	var imported int64
+
+	for start := 0; start < len(links); start += restoreBatchSize {
+		end := min(start+restoreBatchSize, len(links))
+
+		inserted, err := r.insertShortlinks(ctx, r.db, links[start:end])
+		if err != nil {
+			return imported, err
+		}
+		imported += inserted
+	}
+
+	if imported > 0 {
+		_, err = advanceLinkUIDSeqStmt.ExecContext(ctx)
+		if err != nil {
+			return imported, r.log.Wrap(err, "advance link UID sequence")
+		}
+		r.notifyAllChanged(ctx)
+	}
+	return imported, nil
+}
+
+// Backup streams all shortlinks (deleted ones included) with their revisions to the backup storage
+func (r *PostgresRepo) Backup(ctx context.Context) (err error) {
+	ctx, span := startQuerySpan(ctx, "Backup", "SELECT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	var count int
+
+	err = r.backup.BackupEach(ctx, func(yield func(link *entity.Shortlink) error) error {
+		return r.IterateShortlinks(ctx, func(link *entity.Shortlink) error {
+			count++
+			return yield(link)
+		})
+	})
+	if err != nil {
+		return r.log.Wrap(err, "backup")
+	}
+
+	r.log.Info(ctx).Msgf("Backed up %d shortlinks", count)
+	return nil
+}
+
+// Restore loads shortlinks from the backup storage, but only into an empty database,
+// so that a stale backup never overrides links changed since then
+func (r *PostgresRepo) Restore(ctx context.Context) (err error) {
+	ctx, span := startQuerySpan(ctx, "Restore", "INSERT", "shortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	var existing int64
+
+	err = countShortlinksStmt.QueryRowContext(ctx).Scan(&existing)
+	if err != nil {
+		return r.log.Wrap(err, "count shortlinks")
+	}
+	if existing > 0 {
+		r.log.Info(ctx).Msgf("Database already has %d shortlinks, restore skipped", existing)
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.log.Wrap(err, "begin tx")
+	}
+
+	var restored int64
+	batch := make([]*entity.Shortlink, 0, restoreBatchSize)
+
+	flush := func() error {
+		inserted, err := r.insertShortlinks(ctx, tx, batch)
+		if err != nil {
+			return err
+		}
+		restored += inserted
+		batch = batch[:0]
+		return nil
+	}
+
+	err = r.backup.RestoreEach(ctx, func(link *entity.Shortlink) error {
+		batch = append(batch, link)
+		if len(batch) < restoreBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err == nil && restored > 0 {
+		_, err = tx.StmtContext(ctx, advanceLinkUIDSeqStmt).ExecContext(ctx)
+	}
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.log.Error(ctx, rollbackErr).Msg("rollback")
+		}
+		return r.log.Wrap(err, "restore")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return r.log.Wrap(err, "commit after restore")
+	}
+	if restored > 0 {
+		r.notifyAllChanged(ctx)
+	}
+
+	r.log.Info(ctx).Msgf("Restored %d shortlinks", restored)
+	return nil
+}
+
+// insertShortlinks inserts links as-is with a single multi-row statement, skipping the conflicting ones.
+// Revisions are inserted only for the links that were
+func (r *PostgresRepo) insertShortlinks(ctx context.Context, db execer, links []*entity.Shortlink) (int64, error) {
+	const columns = 10
+
+	var query strings.Builder
+	args := make([]any, 0, len(links)*columns)
+
+	query.WriteString("INSERT INTO shortlinks(" + shortlinkColumns + ") VALUES ")
+	for i, link := range links {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := i * columns
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10)
+		args = append(args, link.UID, link.UserUID, link.Short, link.Long, link.Deleted, link.CorrelationID, link.CreatedAt, link.UpdatedAt, link.ExpiresAt, link.DeletedAt)
+	}
+	query.WriteString(" ON CONFLICT DO NOTHING RETURNING link_uid")
+
+	rows, err := db.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return 0, r.log.Wrap(err, "insert")
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]bool, len(links))
+	for rows.Next() {
+		var linkUID string
+		err = rows.Scan(&linkUID)
+		if err != nil {
+			return 0, r.log.Wrap(err, "scan")
+		}
+		inserted[linkUID] = true
+	}
+	err = rows.Err()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows next")
+	}
+
+	var revisions []*entity.ShortlinkRevision
+	for _, link := range links {
+		if !inserted[link.UID] {
+			continue
+		}
+		for _, revision := range link.Revisions {
+			revisions = append(revisions, &entity.ShortlinkRevision{LinkUID: link.UID, Long: revision.Long, ReplacedAt: revision.ReplacedAt})
+		}
+	}
+	err = r.insertRevisions(ctx, db, revisions)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(inserted)), nil
+}
+
+// insertRevisions keeps the given order (oldest first), so that the IDs order the history
+func (r *PostgresRepo) insertRevisions(ctx context.Context, db execer, revisions []*entity.ShortlinkRevision) error {
+	const columns = 3
+
+	for start := 0; start < len(revisions); start += restoreBatchSize {
+		batch := revisions[start:min(start+restoreBatchSize, len(revisions))]
+
+		var query strings.Builder
+		args := make([]any, 0, len(batch)*columns)
+
+		query.WriteString("INSERT INTO shortlink_revisions(link_uid, long, replaced_at) VALUES ")
+		for i, revision := range batch {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			n := i * columns
+			fmt.Fprintf(&query, "($%d, $%d, $%d)", n+1, n+2, n+3)
+			args = append(args, revision.LinkUID, revision.Long, revision.ReplacedAt)
+		}
+
+		_, err := db.ExecContext(ctx, query.String(), args...)
+		if err != nil {
+			return r.log.Wrap(err, "insert revisions")
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRepo) Close(ctx context.Context) error {
+	err := r.backup.Close(ctx)
+	if err != nil {
+		return r.log.Wrap(err, "close backup")
+	}
+
+	err = r.closeStatements(ctx)
+	if err != nil {
+		return r.log.Wrap(err, "close statements")
+	}
+
+	err = r.db.Close()
+	if err != nil {
+		return r.log.Wrap(err, "close db")
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) initStatements(ctx context.Context) error {
+	var err error
+
+	saveShortlinkStmt, err = r.db.PrepareContext(ctx,
+		"WITH inserted AS (INSERT INTO shortlinks(link_uid, user_uid, short, long, correlation_id, created_at, updated_at, expires_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)"+
+			" ON CONFLICT (long) DO NOTHING RETURNING "+shortlinkColumns+")"+
+			", existing AS (SELECT "+shortlinkColumns+" FROM shortlinks WHERE long = $4)"+
+			" SELECT *, true AS conflict FROM existing UNION SELECT *, false AS conflict FROM inserted")
+	if err != nil {
+		return r.log.Wrap(err, "prepare saveShortlinkStmt")
+	}
+	findShortlinkStmt, err = r.db.PrepareContext(ctx, "SELECT "+shortlinkColumns+" FROM shortlinks WHERE link_uid = $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare findShortlinkStmt")
+	}
+	findShortlinksStmt, err = r.db.PrepareContext(ctx, "SELECT "+shortlinkColumns+" FROM shortlinks WHERE link_uid = ANY($1)")
+	if err != nil {
+		return r.log.Wrap(err, "prepare findShortlinksStmt")
+	}
+	findShortlinkByUserStmt, err = r.db.PrepareContext(ctx, "SELECT "+shortlinkColumns+" FROM shortlinks WHERE link_uid = $1 AND user_uid = $2 AND deleted = false")
+	if err != nil {
+		return r.log.Wrap(err, "prepare findShortlinkByUserStmt")
+	}
+	lockShortlinkByUserStmt, err = r.db.PrepareContext(ctx,
+		"SELECT "+shortlinkColumns+" FROM shortlinks WHERE link_uid = $1 AND user_uid = $2 AND deleted = false FOR UPDATE")
+	if err != nil {
+		return r.log.Wrap(err, "prepare lockShortlinkByUserStmt")
+	}
+	findShortlinkByLongStmt, err = r.db.PrepareContext(ctx, "SELECT "+shortlinkColumns+" FROM shortlinks WHERE long = $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare findShortlinkByLongStmt")
+	}
+	updateShortlinkLongStmt, err = r.db.PrepareContext(ctx,
+		"UPDATE shortlinks SET long = $2, updated_at = $3 WHERE link_uid = $1 RETURNING "+shortlinkColumns)
+	if err != nil {
+		return r.log.Wrap(err, "prepare updateShortlinkLongStmt")
+	}
+	saveRevisionStmt, err = r.db.PrepareContext(ctx, "INSERT INTO shortlink_revisions(link_uid, long, replaced_at) VALUES($1, $2, $3)")
+	if err != nil {
+		return r.log.Wrap(err, "prepare saveRevisionStmt")
+	}
+	getRevisionsStmt, err = r.db.PrepareContext(ctx,
+		"SELECT link_uid, long, replaced_at FROM shortlink_revisions WHERE link_uid = $1 ORDER BY id DESC")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getRevisionsStmt")
+	}
+	getRevisionsOfShortlinksStmt, err = r.db.PrepareContext(ctx,
+		"SELECT link_uid, long, replaced_at FROM shortlink_revisions WHERE link_uid = ANY($1) ORDER BY id")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getRevisionsOfShortlinksStmt")
+	}
+	nextLinkNumbersStmt, err = r.db.PrepareContext(ctx, "SELECT nextval('link_uid_seq') FROM generate_series(1, $1) ORDER BY 1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare nextLinkNumbersStmt")
+	}
+	advanceLinkUIDSeqStmt, err = r.db.PrepareContext(ctx,
+		"SELECT setval('link_uid_seq', greatest((SELECT last_value FROM link_uid_seq), (SELECT count(*) FROM shortlinks)))")
+	if err != nil {
+		return r.log.Wrap(err, "prepare advanceLinkUIDSeqStmt")
+	}
+	notifyStmt, err = r.db.PrepareContext(ctx, "SELECT pg_notify($1, $2)")
+	if err != nil {
+		return r.log.Wrap(err, "prepare notifyStmt")
+	}
+	deleteShortlinksStmt, err = r.db.PrepareContext(ctx,
+		"UPDATE shortlinks SET deleted = true, deleted_at = $2, updated_at = $2 WHERE user_uid = $1 AND deleted = false AND link_uid = ANY($3) RETURNING link_uid")
+	if err != nil {
+		return r.log.Wrap(err, "prepare deleteShortlinksStmt")
+	}
+	undeleteShortlinksStmt, err = r.db.PrepareContext(ctx,
+		"UPDATE shortlinks SET deleted = false, deleted_at = NULL, updated_at = $3 WHERE user_uid = $1 AND deleted = true AND link_uid = ANY($2) RETURNING link_uid")
+	if err != nil {
+		return r.log.Wrap(err, "prepare undeleteShortlinksStmt")
+	}
+	getDeletedShortlinksStmt, err = r.db.PrepareContext(ctx,
+		"SELECT "+shortlinkColumns+" FROM shortlinks WHERE user_uid = $1 AND deleted = true ORDER BY deleted_at DESC")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getDeletedShortlinksStmt")
+	}
+	purgeDeletedShortlinksStmt, err = r.db.PrepareContext(ctx, "DELETE FROM shortlinks WHERE deleted = true AND deleted_at < $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare purgeDeletedShortlinksStmt")
+	}
+	deleteExpiredShortlinksStmt, err = r.db.PrepareContext(ctx, "DELETE FROM shortlinks WHERE expires_at IS NOT NULL AND expires_at <= $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare deleteExpiredShortlinksStmt")
+	}
+	countShortlinksStmt, err = r.db.PrepareContext(ctx, "SELECT count(*) FROM shortlinks")
+	if err != nil {
+		return r.log.Wrap(err, "prepare countShortlinksStmt")
+	}
+	getAllShortlinksStmt, err = r.db.PrepareContext(ctx, "SELECT "+shortlinkColumns+", id FROM shortlinks WHERE id > $1 ORDER BY id LIMIT $2")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getAllShortlinksStmt")
+	}
+	saveClickStmt, err = r.db.PrepareContext(ctx, "INSERT INTO clicks(link_uid, clicked_at, referrer, user_agent, ip) VALUES($1, $2, $3, $4, $5)")
+	if err != nil {
+		return r.log.Wrap(err, "prepare saveClickStmt")
+	}
+	countClicksStmt, err = r.db.PrepareContext(ctx,
+		"SELECT count(*), count(DISTINCT (ip, user_agent)) FROM clicks WHERE link_uid = $1 AND clicked_at >= $2 AND clicked_at < $3")
+	if err != nil {
+		return r.log.Wrap(err, "prepare countClicksStmt")
+	}
+	getClickBucketsStmt, err = r.db.PrepareContext(ctx,
+		"SELECT date_trunc($4, clicked_at, 'UTC') AS bucket, count(*) FROM clicks"+
+			" WHERE link_uid = $1 AND clicked_at >= $2 AND clicked_at < $3 GROUP BY bucket ORDER BY bucket")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getClickBucketsStmt")
+	}
+	getTopClickReferrersStmt, err = r.db.PrepareContext(ctx,
+		"SELECT referrer, count(*) AS clicks FROM clicks"+
+			" WHERE link_uid = $1 AND clicked_at >= $2 AND clicked_at < $3 AND referrer <> ''"+
+			" GROUP BY referrer ORDER BY clicks DESC, referrer LIMIT $4")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getTopClickReferrersStmt")
+	}
+	getTopClickUserAgentsStmt, err = r.db.PrepareContext(ctx,
+		"SELECT user_agent, count(*) AS clicks FROM clicks"+
+			" WHERE link_uid = $1 AND clicked_at >= $2 AND clicked_at < $3 AND user_agent <> ''"+
+			" GROUP BY user_agent ORDER BY clicks DESC, user_agent LIMIT $4")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getTopClickUserAgentsStmt")
+	}
+	enqueueDeleteTaskStmt, err = r.db.PrepareContext(ctx,
+		"INSERT INTO delete_outbox(user_uid, link_uids, attempts, next_attempt_at, created_at) VALUES($1, $2, $3, $4, $5) RETURNING id")
+	if err != nil {
+		return r.log.Wrap(err, "prepare enqueueDeleteTaskStmt")
+	}
+	getDeleteTaskStmt, err = r.db.PrepareContext(ctx, "SELECT "+deleteTaskColumns+" FROM delete_outbox WHERE id = $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getDeleteTaskStmt")
+	}
+	getDueDeleteTasksStmt, err = r.db.PrepareContext(ctx,
+		"SELECT "+deleteTaskColumns+" FROM delete_outbox WHERE dead_at IS NULL AND done_at IS NULL AND next_attempt_at <= $1 ORDER BY id LIMIT $2")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getDueDeleteTasksStmt")
+	}
+	countPendingDeleteTasksStmt, err = r.db.PrepareContext(ctx, "SELECT count(*) FROM delete_outbox WHERE dead_at IS NULL AND done_at IS NULL")
+	if err != nil {
+		return r.log.Wrap(err, "prepare countPendingDeleteTasksStmt")
+	}
+	completeDeleteTaskStmt, err = r.db.PrepareContext(ctx, "UPDATE delete_outbox SET attempts = $2, deleted_count = $3, not_found = $4, done_at = $5 WHERE id = $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare completeDeleteTaskStmt")
+	}
+	retryDeleteTaskStmt, err = r.db.PrepareContext(ctx, "UPDATE delete_outbox SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare retryDeleteTaskStmt")
+	}
+	deadLetterDeleteTaskStmt, err = r.db.PrepareContext(ctx, "UPDATE delete_outbox SET attempts = $2, last_error = $3, dead_at = $4 WHERE id = $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare deadLetterDeleteTaskStmt")
+	}
+	getDeadDeleteTasksStmt, err = r.db.PrepareContext(ctx, "SELECT "+deleteTaskColumns+" FROM delete_outbox WHERE dead_at IS NOT NULL ORDER BY id")
+	if err != nil {
+		return r.log.Wrap(err, "prepare getDeadDeleteTasksStmt")
+	}
+	purgeDeleteTasksStmt, err = r.db.PrepareContext(ctx, "DELETE FROM delete_outbox WHERE done_at IS NOT NULL AND done_at < $1")
+	if err != nil {
+		return r.log.Wrap(err, "prepare purgeDeleteTasksStmt")
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) closeStatements(ctx context.Context) error {
+	if err := saveShortlinkStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close saveShortlinkStmt")
+	}
+	if err := findShortlinkStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close findShortlinkStmt")
+	}
+	if err := findShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close findShortlinksStmt")
+	}
+	if err := findShortlinkByUserStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close findShortlinkByUserStmt")
+	}
+	if err := lockShortlinkByUserStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close lockShortlinkByUserStmt")
+	}
+	if err := findShortlinkByLongStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close findShortlinkByLongStmt")
+	}
+	if err := updateShortlinkLongStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close updateShortlinkLongStmt")
+	}
+	if err := saveRevisionStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close saveRevisionStmt")
+	}
+	if err := getRevisionsStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getRevisionsStmt")
+	}
+	if err := getRevisionsOfShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getRevisionsOfShortlinksStmt")
+	}
+	if err := nextLinkNumbersStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close nextLinkNumbersStmt")
+	}
+	if err := advanceLinkUIDSeqStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close advanceLinkUIDSeqStmt")
+	}
+	if err := notifyStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close notifyStmt")
+	}
+	if err := deleteShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close deleteShortlinksStmt")
+	}
+	if err := undeleteShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close undeleteShortlinksStmt")
+	}
+	if err := getDeletedShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getDeletedShortlinksStmt")
+	}
+	if err := purgeDeletedShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close purgeDeletedShortlinksStmt")
+	}
+	if err := deleteExpiredShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close deleteExpiredShortlinksStmt")
+	}
+	if err := countShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close countShortlinksStmt")
+	}
+	if err := getAllShortlinksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getAllShortlinksStmt")
+	}
+	if err := saveClickStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close saveClickStmt")
+	}
+	if err := countClicksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close countClicksStmt")
+	}
+	if err := getClickBucketsStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getClickBucketsStmt")
+	}
+	if err := getTopClickReferrersStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getTopClickReferrersStmt")
+	}
+	if err := getTopClickUserAgentsStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getTopClickUserAgentsStmt")
+	}
+	if err := enqueueDeleteTaskStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close enqueueDeleteTaskStmt")
+	}
+	if err := getDeleteTaskStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getDeleteTaskStmt")
+	}
+	if err := getDueDeleteTasksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getDueDeleteTasksStmt")
+	}
+	if err := countPendingDeleteTasksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close countPendingDeleteTasksStmt")
+	}
+	if err := completeDeleteTaskStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close completeDeleteTaskStmt")
+	}
+	if err := retryDeleteTaskStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close retryDeleteTaskStmt")
+	}
+	if err := deadLetterDeleteTaskStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close deadLetterDeleteTaskStmt")
+	}
+	if err := getDeadDeleteTasksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close getDeadDeleteTasksStmt")
+	}
+	if err := purgeDeleteTasksStmt.Close(); err != nil {
+		return r.log.Wrap(err, "close purgeDeleteTasksStmt")
+	}
+	return nil
+}
+
+// startQuerySpan starts a span for a repo operation, operation and table are empty if not applicable
+func startQuerySpan(ctx context.Context, name, operation, table string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+	if operation != "" {
+		attrs = append(attrs, semconv.DBOperation(operation))
+	}
+	if table != "" {
+		attrs = append(attrs, semconv.DBSQLTable(table))
+	}
+	return tracing.Start(ctx, "PostgresRepo."+name, attrs...)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// execer is either *sql.DB or *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// shortlinkOrder returns the sort column, its direction and the operator that selects links after the cursor
+func shortlinkOrder(query entity.ShortlinkQuery) (column, direction, op string) {
+	column, direction, op = "created_at", "ASC", ">"
+	if query.Sort == entity.ShortlinkSortLong {
+		column = "long"
+	}
+	if query.Desc {
+		direction, op = "DESC", "<"
+	}
+	return column, direction, op
+}
+
+// scanShortlink scans a row selected with shortlinkColumns, followed by optional extra columns
+func scanShortlink(row rowScanner, extra ...any) (*entity.Shortlink, error) {
+	link := new(entity.Shortlink)
+	var corrID sql.NullString
+	var expiresAt, deletedAt sql.NullTime
+
+	dest := append([]any{&link.UID, &link.UserUID, &link.Short, &link.Long, &link.Deleted, &corrID, &link.CreatedAt, &link.UpdatedAt, &expiresAt, &deletedAt}, extra...)
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	link.CorrelationID = corrID.String
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if deletedAt.Valid {
+		link.DeletedAt = &deletedAt.Time
+	}
+
+	return link, nil
+}
+
+// isURLConflict is true for unique violations other than the link UID one, i.e. on the long URL
+func isURLConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName != linkUIDUniqueKey
+}
+
+func isUIDConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName == linkUIDUniqueKey
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/linkid"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/storage"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+type (
+	// Backend is a set of repositories sharing the same underlying storage
+	Backend struct {
+		Shortlinks  ShortlinkRepo
+		Clicks      ClickRepo
+		DeleteQueue DeleteQueueRepo
+		// Storage is where Shortlinks are backed up to
+		Storage storage.Storage
+		// IDSequence is shared by all instances using the backend, nil if the backend has none
+		IDSequence linkid.Sequence
+		// Notifications tell which links were changed by other instances, nil if the backend has none
+		Notifications NotificationSource
+	}
+	BackendConstructor func(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Backend, error)
+)
+
+var (
+	backends      = make(map[string]BackendConstructor)
+	backendsMutex sync.RWMutex
+)
+
+func init() {
+	RegisterBackend(config.StorageBackendMemory, newMemoryBackend)
+	RegisterBackend(config.StorageBackendFile, newFileBackend)
+	RegisterBackend(config.StorageBackendPostgres, newPostgresBackend)
+	RegisterBackend(config.StorageBackendSQLite, newSQLiteBackend)
+}
+
+func RegisterBackend(name string, constructor BackendConstructor) {
+	backendsMutex.Lock()
+	defer backendsMutex.Unlock()
+
+	backends[name] = constructor
+}
+
+func Backends() []string {
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewBackend(ctx context.Context, name string, cfg *config.Config, log *logger.Logger) (*Backend, error) {
+	backendsMutex.RLock()
+	constructor, ok := backends[name]
+	backendsMutex.RUnlock()
+
+	if !ok {
+		return nil, log.Wrapf(ErrUnknownBackend, "backend = %s", name)
+	}
+	return constructor(ctx, cfg, log)
+}
+
+// newMemoryBackend keeps everything in memory only, data is lost on restart
+func newMemoryBackend(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Backend, error) {
+	backup, err := storage.NewFileStorage("")
+	if err != nil {
+		return nil, log.Wrap(err, "init backup storage")
+	}
+
+	deleteQueue, err := NewInMemDeleteQueue("", log.SubLogger("delete_queue"))
+	if err != nil {
+		return nil, log.Wrap(err, "init delete queue")
+	}
+
+	return &Backend{
+		Shortlinks:  NewInMemShortlinkRepo(backup),
+		Clicks:      NewInMemClickRepo(cfg.Analytics.InMemCapacity),
+		DeleteQueue: deleteQueue,
+		Storage:     backup,
+	}, nil
+}
+
+// newFileBackend keeps everything in memory, backed up to a file (either a shutdown snapshot or a journal)
+func newFileBackend(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Backend, error) {
+	if cfg.Storage.Filepath == "" {
+		return nil, log.Wrap(ErrBackendMisconfigured, "file storage path is empty")
+	}
+
+	var backup storage.Storage
+	var err error
+
+	switch cfg.Storage.Mode {
+	case config.FileStorageModeJournal:
+		switch cfg.Storage.FsyncPolicy {
+		case config.FsyncPolicyAlways, config.FsyncPolicyInterval, config.FsyncPolicyNever:
+		default:
+			return nil, log.Wrapf(ErrBackendMisconfigured, "unknown fsync policy %q", cfg.Storage.FsyncPolicy)
+		}
+		backup, err = storage.NewFileJournal(cfg.Storage, log.SubLogger("file_journal"))
+	case config.FileStorageModeSnapshot, "":
+		backup, err = storage.NewFileStorage(cfg.Storage.Filepath)
+	default:
+		return nil, log.Wrapf(ErrBackendMisconfigured, "unknown file storage mode %q", cfg.Storage.Mode)
+	}
+	if err != nil {
+		return nil, log.Wrap(err, "init backup storage")
+	}
+	log.Info(ctx).Msgf("Initialized backup storage @ %s (%s)", cfg.Storage.Filepath, cfg.Storage.Mode)
+
+	// Pending deletes are journaled next to the backup, unless configured otherwise
+	deleteQueuePath := cfg.Batch.DeleteQueuePath
+	if deleteQueuePath == "" {
+		deleteQueuePath = cfg.Storage.Filepath + ".deletes"
+	}
+	deleteQueue, err := NewInMemDeleteQueue(deleteQueuePath, log.SubLogger("delete_queue"))
+	if err != nil {
+		if closeErr := backup.Close(ctx); closeErr != nil {
+			log.Error(ctx, closeErr).Msg("close backup storage")
+		}
+		return nil, log.Wrap(err, "init delete queue")
+	}
+
+	return &Backend{
+		Shortlinks:  NewInMemShortlinkRepo(backup),
+		Clicks:      NewInMemClickRepo(cfg.Analytics.InMemCapacity),
+		DeleteQueue: deleteQueue,
+		Storage:     backup,
+	}, nil
+}
+
+func newPostgresBackend(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Backend, error) {
+	if cfg.PostgreSQL.ConnString == "" {
+		return nil, log.Wrap(ErrBackendMisconfigured, "database connection string is empty")
+	}
+
+	backup, err := storage.NewFileStorage(cfg.Storage.Filepath)
+	if err != nil {
+		return nil, log.Wrap(err, "init backup storage")
+	}
+
+	repo, err := NewPostgresRepo(ctx, cfg.PostgreSQL, backup, log)
+	if err != nil {
+		if closeErr := backup.Close(ctx); closeErr != nil {
+			log.Error(ctx, closeErr).Msg("close backup storage")
+		}
+		return nil, err
+	}
+
+	return &Backend{
+		Shortlinks:    repo,
+		Clicks:        repo,
+		DeleteQueue:   repo,
+		Storage:       backup,
+		IDSequence:    NewPostgresSequence(repo, cfg.Shortener.IDRangeSize),
+		Notifications: NewPostgresNotifier(cfg.PostgreSQL.ConnString),
+	}, nil
+}
+
+func newSQLiteBackend(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Backend, error) {
+	if cfg.SQLite.Path == "" {
+		return nil, log.Wrap(ErrBackendMisconfigured, "SQLite database path is empty")
+	}
+
+	backup, err := storage.NewFileStorage(cfg.Storage.Filepath)
+	if err != nil {
+		return nil, log.Wrap(err, "init backup storage")
+	}
+
+	repo, err := NewSQLiteRepo(ctx, cfg.SQLite, backup, log)
+	if err != nil {
+		if closeErr := backup.Close(ctx); closeErr != nil {
+			log.Error(ctx, closeErr).Msg("close backup storage")
+		}
+		return nil, err
+	}
+
+	return &Backend{
+		Shortlinks:  repo,
+		Clicks:      repo,
+		DeleteQueue: repo,
+		Storage:     backup,
+	}, nil
+}
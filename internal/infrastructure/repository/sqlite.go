@@ -0,0 +1,993 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/storage"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+// Timestamps are stored as unix milliseconds (UTC), so they compare and bucket as plain integers
+const (
+	// 10 parameters per inserted shortlink, well below the default SQLite limit of 32766
+	sqliteInsertBatchSize = 1000
+
+	sqliteShortlinkColumns = "link_uid, user_uid, short, long, deleted, correlation_id, created_at, updated_at, expires_at, deleted_at"
+
+	sqliteSaveShortlinkQuery = "INSERT INTO shortlinks(link_uid, user_uid, short, long, correlation_id, created_at, updated_at, expires_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?)" +
+		" ON CONFLICT (long) DO NOTHING"
+	sqliteFindShortlinkByLongQuery  = "SELECT " + sqliteShortlinkColumns + " FROM shortlinks WHERE long = ?"
+	sqliteFindShortlinkQuery        = "SELECT " + sqliteShortlinkColumns + " FROM shortlinks WHERE link_uid = ?"
+	sqliteFindShortlinkByUserQuery  = "SELECT " + sqliteShortlinkColumns + " FROM shortlinks WHERE link_uid = ? AND user_uid = ? AND deleted = false"
+	sqliteGetShortlinksPageQuery    = "SELECT " + sqliteShortlinkColumns + ", id FROM shortlinks WHERE id > ? ORDER BY id LIMIT ?"
+	sqliteCountShortlinksQuery      = "SELECT count(*) FROM shortlinks"
+	sqliteGetDeletedByUserQuery     = "SELECT " + sqliteShortlinkColumns + " FROM shortlinks WHERE user_uid = ? AND deleted = true ORDER BY deleted_at DESC"
+	sqliteUpdateShortlinkLongQuery  = "UPDATE shortlinks SET long = ?, updated_at = ? WHERE link_uid = ?"
+	sqliteSaveRevisionQuery         = "INSERT INTO shortlink_revisions(link_uid, long, replaced_at) VALUES(?, ?, ?)"
+	sqliteGetRevisionsQuery         = "SELECT link_uid, long, replaced_at FROM shortlink_revisions WHERE link_uid = ? ORDER BY id DESC"
+	sqlitePurgeDeletedLinksQuery    = "DELETE FROM shortlinks WHERE deleted = true AND deleted_at < ?"
+	sqliteDeleteExpiredLinksQuery   = "DELETE FROM shortlinks WHERE expires_at IS NOT NULL AND expires_at <= ?"
+	sqliteSaveClickQuery            = "INSERT INTO clicks(link_uid, clicked_at, referrer, user_agent, ip) VALUES(?, ?, ?, ?, ?)"
+	sqliteCountClicksQuery          = "SELECT count(*), count(DISTINCT ip || '|' || user_agent) FROM clicks WHERE link_uid = ? AND clicked_at >= ? AND clicked_at < ?"
+	sqliteGetClickBucketsQuery      = "SELECT (clicked_at / ?) * ? AS bucket, count(*) FROM clicks WHERE link_uid = ? AND clicked_at >= ? AND clicked_at < ? GROUP BY bucket ORDER BY bucket"
+	sqliteGetTopClickReferrersQuery = "SELECT referrer, count(*) AS clicks FROM clicks WHERE link_uid = ? AND clicked_at >= ? AND clicked_at < ? AND referrer <> ''" +
+		" GROUP BY referrer ORDER BY clicks DESC, referrer LIMIT ?"
+	sqliteGetTopClickUserAgentsQuery = "SELECT user_agent, count(*) AS clicks FROM clicks WHERE link_uid = ? AND clicked_at >= ? AND clicked_at < ? AND user_agent <> ''" +
+		" GROUP BY user_agent ORDER BY clicks DESC, user_agent LIMIT ?"
+
+	sqliteDeleteTaskColumns         = "id, user_uid, link_uids, attempts, next_attempt_at, last_error, deleted_count, not_found, created_at, done_at, dead_at"
+	sqliteEnqueueDeleteTaskQuery    = "INSERT INTO delete_outbox(user_uid, link_uids, attempts, next_attempt_at, created_at) VALUES(?, ?, ?, ?, ?) RETURNING id"
+	sqliteGetDeleteTaskQuery        = "SELECT " + sqliteDeleteTaskColumns + " FROM delete_outbox WHERE id = ?"
+	sqliteGetDueDeleteTasksQuery    = "SELECT " + sqliteDeleteTaskColumns + " FROM delete_outbox WHERE dead_at IS NULL AND done_at IS NULL AND next_attempt_at <= ? ORDER BY id LIMIT ?"
+	sqliteCountPendingTasksQuery    = "SELECT count(*) FROM delete_outbox WHERE dead_at IS NULL AND done_at IS NULL"
+	sqliteCompleteDeleteTaskQuery   = "UPDATE delete_outbox SET attempts = ?, deleted_count = ?, not_found = ?, done_at = ? WHERE id = ?"
+	sqliteRetryDeleteTaskQuery      = "UPDATE delete_outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?"
+	sqliteDeadLetterDeleteTaskQuery = "UPDATE delete_outbox SET attempts = ?, last_error = ?, dead_at = ? WHERE id = ?"
+	sqliteGetDeadDeleteTasksQuery   = "SELECT " + sqliteDeleteTaskColumns + " FROM delete_outbox WHERE dead_at IS NOT NULL ORDER BY id"
+	sqlitePurgeDeleteTasksQuery     = "DELETE FROM delete_outbox WHERE done_at IS NOT NULL AND done_at < ?"
+)
+
+func init() {
+	// SQLite cannot parse URLs, the domain filter of user links is done in Go
+	sqlite.MustRegisterDeterministicScalarFunction("url_matches_domain", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		long, _ := args[0].(string)
+		domain, _ := args[1].(string)
+		link := &entity.Shortlink{Long: long}
+		return entity.MatchesDomain(link.Host(), domain), nil
+	})
+}
+
+type SQLiteRepo struct {
+	cfg    config.SQLite
+	db     *sql.DB
+	backup storage.Storage
+	log    *logger.Logger
+}
+
+func NewSQLiteRepo(ctx context.Context, cfg config.SQLite, backup storage.Storage, log *logger.Logger) (*SQLiteRepo, error) {
+	db, err := sql.Open("sqlite", cfg.Path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, log.Wrap(err, "open")
+	}
+	// SQLite allows a single writer at a time, serialize access instead of getting SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	r := &SQLiteRepo{
+		cfg:    cfg,
+		db:     db,
+		backup: backup,
+		log:    log,
+	}
+
+	err = r.Ping(ctx)
+	if err != nil {
+		return nil, log.Wrap(err, "ping")
+	}
+
+	// Migrations
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return nil, log.Wrap(err, "init migrator driver")
+	}
+	migrator, err := migrate.NewWithDatabaseInstance("file://"+cfg.MigrationsPath, "sqlite", driver)
+	if err != nil {
+		return nil, log.Wrap(err, "init migrator")
+	}
+
+	err = migrator.Up()
+	if err == nil {
+		log.Info(ctx).Msg("Migrations: done")
+	} else {
+		if !errors.Is(err, migrate.ErrNoChange) {
+			return nil, log.Wrap(err, "migrations")
+		}
+		log.Info(ctx).Msg("Migrations: no changes")
+	}
+
+	return r, nil
+}
+
+func (r *SQLiteRepo) Ping(ctx context.Context) error {
+	err := r.db.PingContext(ctx)
+	if err != nil {
+		return r.log.Wrap(err, "ping")
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) SaveShortlink(ctx context.Context, link *entity.Shortlink) (*entity.Shortlink, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, r.log.Wrap(err, "begin tx")
+	}
+
+	result, err := r.saveShortlink(ctx, tx, link)
+	if err != nil && !errors.Is(err, ErrURLConflict) {
+		r.rollback(ctx, tx)
+		return nil, err
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		return nil, r.log.Wrap(commitErr, "commit after insert")
+	}
+
+	return result, err
+}
+
+func (r *SQLiteRepo) SaveShortlinks(ctx context.Context, links []*entity.Shortlink) ([]*entity.Shortlink, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, r.log.Wrap(err, "begin tx")
+	}
+
+	var result []*entity.Shortlink
+
+	for _, link := range links {
+		resultLink, err := r.saveShortlink(ctx, tx, link)
+		if err != nil && !errors.Is(err, ErrURLConflict) {
+			r.rollback(ctx, tx)
+			return nil, err
+		}
+		result = append(result, resultLink)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, r.log.Wrap(err, "commit after insert")
+	}
+
+	return result, nil
+}
+
+// saveShortlink inserts the link, or returns the existing one with ErrURLConflict if long URL is already shortened
+func (r *SQLiteRepo) saveShortlink(ctx context.Context, tx *sql.Tx, link *entity.Shortlink) (*entity.Shortlink, error) {
+	res, err := tx.ExecContext(ctx, sqliteSaveShortlinkQuery,
+		link.UID, link.UserUID, link.Short, link.Long, link.CorrelationID, link.CreatedAt.UnixMilli(), link.UpdatedAt.UnixMilli(), toUnixMilli(link.ExpiresAt))
+	if err != nil {
+		if isSQLiteUIDConflict(err) {
+			return nil, ErrUIDConflict
+		}
+		return nil, r.log.Wrap(err, "insert")
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows affected")
+	}
+	if inserted > 0 {
+		return link, nil
+	}
+
+	existing, err := scanSQLiteShortlink(tx.QueryRowContext(ctx, sqliteFindShortlinkByLongQuery, link.Long))
+	if err != nil {
+		return nil, r.log.Wrap(err, "select existing")
+	}
+	return existing, ErrURLConflict
+}
+
+func (r *SQLiteRepo) FindShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error) {
+	var row *sql.Row
+
+	if userUID != "" {
+		row = r.db.QueryRowContext(ctx, sqliteFindShortlinkByUserQuery, linkUID, userUID)
+	} else {
+		row = r.db.QueryRowContext(ctx, sqliteFindShortlinkQuery, linkUID)
+	}
+
+	link, err := scanSQLiteShortlink(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, r.log.Wrap(err, "scan")
+	}
+
+	return link, nil
+}
+
+func (r *SQLiteRepo) FindShortlinks(ctx context.Context, linkUIDs []string) ([]*entity.Shortlink, error) {
+	if len(linkUIDs) == 0 {
+		return nil, nil
+	}
+
+	query := "SELECT " + sqliteShortlinkColumns + " FROM shortlinks WHERE link_uid IN (" + placeholders(len(linkUIDs)) + ")"
+	args := make([]any, len(linkUIDs))
+	for i, linkUID := range linkUIDs {
+		args[i] = linkUID
+	}
+
+	return r.queryShortlinks(ctx, query, args...)
+}
+
+func (r *SQLiteRepo) GetShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (*entity.ShortlinkPage, error) {
+	conditions := []string{"user_uid = ?", "deleted = false"}
+	args := []any{userUID}
+
+	if query.Domain != "" {
+		conditions = append(conditions, "url_matches_domain(long, ?)")
+		args = append(args, query.Domain)
+	}
+	if query.Search != "" {
+		conditions = append(conditions, "instr(lower(long), ?) > 0")
+		args = append(args, strings.ToLower(query.Search))
+	}
+	if query.CreatedAfter != nil {
+		conditions = append(conditions, "created_at > ?")
+		args = append(args, query.CreatedAfter.UnixMilli())
+	}
+
+	column, direction, op := shortlinkOrder(query)
+	if query.After != nil {
+		conditions = append(conditions, "("+column+", link_uid) "+op+" (?, ?)")
+		if query.Sort == entity.ShortlinkSortLong {
+			args = append(args, query.After.Long, query.After.UID)
+		} else {
+			args = append(args, query.After.CreatedAt.UnixMilli(), query.After.UID)
+		}
+	}
+
+	selectQuery := "SELECT " + sqliteShortlinkColumns + " FROM shortlinks WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + column + " " + direction + ", link_uid " + direction
+	if query.Limit > 0 {
+		selectQuery += " LIMIT ?"
+		args = append(args, query.Limit+1)
+	}
+
+	links, err := r.queryShortlinks(ctx, selectQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	return newShortlinkPage(query, links), nil
+}
+
+func (r *SQLiteRepo) queryShortlinks(ctx context.Context, query string, args ...any) ([]*entity.Shortlink, error) {
+	var links []*entity.Shortlink
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		link, err := scanSQLiteShortlink(rows)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		links = append(links, link)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+
+	return links, nil
+}
+
+func (r *SQLiteRepo) UpdateShortlinkLong(ctx context.Context, userUID, linkUID, long string) (*entity.Shortlink, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, r.log.Wrap(err, "begin tx")
+	}
+
+	link, err := scanSQLiteShortlink(tx.QueryRowContext(ctx, sqliteFindShortlinkByUserQuery, linkUID, userUID))
+	if err != nil {
+		r.rollback(ctx, tx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, r.log.Wrap(err, "select")
+	}
+	if link.Long == long {
+		r.rollback(ctx, tx)
+		return link, nil
+	}
+
+	existing, err := scanSQLiteShortlink(tx.QueryRowContext(ctx, sqliteFindShortlinkByLongQuery, long))
+	if err == nil {
+		r.rollback(ctx, tx)
+		return existing, ErrURLConflict
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		r.rollback(ctx, tx)
+		return nil, r.log.Wrap(err, "select existing")
+	}
+
+	now := time.Now().UTC()
+
+	_, err = tx.ExecContext(ctx, sqliteUpdateShortlinkLongQuery, long, now.UnixMilli(), linkUID)
+	if err != nil {
+		r.rollback(ctx, tx)
+		return nil, r.log.Wrap(err, "update")
+	}
+	_, err = tx.ExecContext(ctx, sqliteSaveRevisionQuery, linkUID, link.Long, now.UnixMilli())
+	if err != nil {
+		r.rollback(ctx, tx)
+		return nil, r.log.Wrap(err, "insert revision")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, r.log.Wrap(err, "commit after update")
+	}
+
+	link.Long = long
+	link.UpdatedAt = now.Truncate(time.Millisecond)
+	return link, nil
+}
+
+func (r *SQLiteRepo) GetShortlinkRevisions(ctx context.Context, linkUID string) ([]*entity.ShortlinkRevision, error) {
+	var revisions []*entity.ShortlinkRevision
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetRevisionsQuery, linkUID)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var replacedAt int64
+		revision := new(entity.ShortlinkRevision)
+		err := rows.Scan(&revision.LinkUID, &revision.Long, &replacedAt)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		revision.ReplacedAt = time.UnixMilli(replacedAt).UTC()
+		revisions = append(revisions, revision)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+
+	return revisions, nil
+}
+
+func (r *SQLiteRepo) DeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	return r.setDeleted(ctx, userUID, linkUIDs, true)
+}
+
+func (r *SQLiteRepo) UndeleteShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error) {
+	return r.setDeleted(ctx, userUID, linkUIDs, false)
+}
+
+func (r *SQLiteRepo) GetDeletedShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error) {
+	return r.queryShortlinks(ctx, sqliteGetDeletedByUserQuery, userUID)
+}
+
+func (r *SQLiteRepo) PurgeDeletedShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, sqlitePurgeDeletedLinksQuery, before.UnixMilli())
+	if err != nil {
+		return 0, r.log.Wrap(err, "delete trashed shortlinks")
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows affected")
+	}
+	return purged, nil
+}
+
+// setDeleted returns UIDs of the updated links
+func (r *SQLiteRepo) setDeleted(ctx context.Context, userUID string, linkUIDs []string, deleted bool) ([]string, error) {
+	if len(linkUIDs) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	var deletedAt *time.Time
+	if deleted {
+		deletedAt = &now
+	}
+
+	query := "UPDATE shortlinks SET deleted = ?, deleted_at = ?, updated_at = ? WHERE user_uid = ? AND deleted != ? AND link_uid IN (" + placeholders(len(linkUIDs)) + ") RETURNING link_uid"
+	args := make([]any, 0, len(linkUIDs)+5)
+	args = append(args, deleted, toUnixMilli(deletedAt), now.UnixMilli(), userUID, deleted)
+	for _, linkUID := range linkUIDs {
+		args = append(args, linkUID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, r.log.Wrap(err, "update shortlinks deleted flag")
+	}
+	defer rows.Close()
+
+	var updated []string
+	for rows.Next() {
+		var linkUID string
+		err := rows.Scan(&linkUID)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan")
+		}
+		updated = append(updated, linkUID)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+	return updated, nil
+}
+
+func (r *SQLiteRepo) DeleteExpiredShortlinks(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, sqliteDeleteExpiredLinksQuery, before.UnixMilli())
+	if err != nil {
+		return 0, r.log.Wrap(err, "delete expired shortlinks")
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows affected")
+	}
+	return deleted, nil
+}
+
+func (r *SQLiteRepo) CountShortlinks(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, sqliteCountShortlinksQuery).Scan(&count)
+	if err != nil {
+		return 0, r.log.Wrap(err, "count shortlinks")
+	}
+	return count, nil
+}
+
+// IterateShortlinks reads links page by page with their revisions, so that the only connection is free while fn runs
+func (r *SQLiteRepo) IterateShortlinks(ctx context.Context, fn func(link *entity.Shortlink) error) error {
+	var afterID int64
+	for {
+		links, lastID, err := r.getShortlinksPage(ctx, afterID)
+		if err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		afterID = lastID
+
+		for _, link := range links {
+			err = fn(link)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// getShortlinksPage returns up to sqliteInsertBatchSize links with IDs above afterID along with their revisions,
+// and the ID of the last one
+func (r *SQLiteRepo) getShortlinksPage(ctx context.Context, afterID int64) ([]*entity.Shortlink, int64, error) {
+	rows, err := r.db.QueryContext(ctx, sqliteGetShortlinksPageQuery, afterID, sqliteInsertBatchSize)
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	var links []*entity.Shortlink
+	var lastID int64
+	byUID := make(map[string]*entity.Shortlink)
+	args := make([]any, 0, sqliteInsertBatchSize)
+
+	for rows.Next() {
+		link, err := scanSQLiteShortlink(rows, &lastID)
+		if err != nil {
+			return nil, 0, r.log.Wrap(err, "scan")
+		}
+		links = append(links, link)
+		byUID[link.UID] = link
+		args = append(args, link.UID)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "rows next")
+	}
+	if len(links) == 0 {
+		return nil, 0, nil
+	}
+	_ = rows.Close()
+
+	query := "SELECT link_uid, long, replaced_at FROM shortlink_revisions WHERE link_uid IN (" + placeholders(len(args)) + ") ORDER BY id"
+	revisionRows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "select revisions")
+	}
+	defer revisionRows.Close()
+
+	for revisionRows.Next() {
+		var replacedAt int64
+		revision := new(entity.ShortlinkRevision)
+		err := revisionRows.Scan(&revision.LinkUID, &revision.Long, &replacedAt)
+		if err != nil {
+			return nil, 0, r.log.Wrap(err, "scan revision")
+		}
+		revision.ReplacedAt = time.UnixMilli(replacedAt).UTC()
+		link := byUID[revision.LinkUID]
+		link.Revisions = append(link.Revisions, revision)
+	}
+	err = revisionRows.Err()
+	if err != nil {
+		return nil, 0, r.log.Wrap(err, "revision rows next")
+	}
+
+	return links, lastID, nil
+}
+
+func (r *SQLiteRepo) ImportShortlinks(ctx context.Context, links []*entity.Shortlink) (int64, error) {
+	var imported int64
+
+	for start := 0; start < len(links); start += sqliteInsertBatchSize {
+		inserted, err := r.insertShortlinks(ctx, r.db, links[start:min(start+sqliteInsertBatchSize, len(links))])
+		if err != nil {
+			return imported, err
+		}
+		imported += inserted
+	}
+	return imported, nil
+}
+
+// insertShortlinks inserts links as-is with a single multi-row statement, skipping the conflicting ones.
+// Revisions are inserted only for the links that were
+func (r *SQLiteRepo) insertShortlinks(ctx context.Context, db execer, links []*entity.Shortlink) (int64, error) {
+	query := "INSERT INTO shortlinks(" + sqliteShortlinkColumns + ") VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?), ", len(links)), ", ") +
+		" ON CONFLICT DO NOTHING RETURNING link_uid"
+	args := make([]any, 0, len(links)*10)
+	for _, link := range links {
+		args = append(args, link.UID, link.UserUID, link.Short, link.Long, link.Deleted, link.CorrelationID,
+			link.CreatedAt.UnixMilli(), link.UpdatedAt.UnixMilli(), toUnixMilli(link.ExpiresAt), toUnixMilli(link.DeletedAt))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, r.log.Wrap(err, "insert")
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]bool, len(links))
+	for rows.Next() {
+		var linkUID string
+		err = rows.Scan(&linkUID)
+		if err != nil {
+			return 0, r.log.Wrap(err, "scan")
+		}
+		inserted[linkUID] = true
+	}
+	err = rows.Err()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows next")
+	}
+	_ = rows.Close()
+
+	// Oldest first, so that the IDs order the history
+	for _, link := range links {
+		if !inserted[link.UID] {
+			continue
+		}
+		for _, revision := range link.Revisions {
+			_, err := db.ExecContext(ctx, sqliteSaveRevisionQuery, link.UID, revision.Long, revision.ReplacedAt.UnixMilli())
+			if err != nil {
+				return 0, r.log.Wrap(err, "insert revision")
+			}
+		}
+	}
+
+	return int64(len(inserted)), nil
+}
+
+func (r *SQLiteRepo) SaveClicks(ctx context.Context, clicks []*entity.Click) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.log.Wrap(err, "begin tx")
+	}
+
+	for _, click := range clicks {
+		_, err := tx.ExecContext(ctx, sqliteSaveClickQuery,
+			click.LinkUID, click.Timestamp.UnixMilli(), click.Referrer, click.UserAgent, click.IP)
+		if err != nil {
+			r.rollback(ctx, tx)
+			return r.log.Wrap(err, "insert")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return r.log.Wrap(err, "commit after insert")
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepo) GetClickStats(ctx context.Context, linkUID string, query entity.ClickStatsQuery) (*entity.ClickStats, error) {
+	stats := new(entity.ClickStats)
+	from, to := query.From.UnixMilli(), query.To.UnixMilli()
+
+	row := r.db.QueryRowContext(ctx, sqliteCountClicksQuery, linkUID, from, to)
+	err := row.Scan(&stats.Total, &stats.UniqueVisitors)
+	if err != nil {
+		return nil, r.log.Wrap(err, "count clicks")
+	}
+
+	bucketSize := time.Hour.Milliseconds()
+	if query.Bucket == entity.ClickBucketDay {
+		bucketSize = (24 * time.Hour).Milliseconds()
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetClickBucketsQuery, bucketSize, bucketSize, linkUID, from, to)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select click buckets")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start int64
+		var bucket entity.ClickBucket
+		err := rows.Scan(&start, &bucket.Count)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan click bucket")
+		}
+		bucket.Start = time.UnixMilli(start).UTC()
+		stats.Buckets = append(stats.Buckets, bucket)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+
+	stats.TopReferrers, err = r.getTopClickCounters(ctx, sqliteGetTopClickReferrersQuery, linkUID, from, to, query.Top)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select top referrers")
+	}
+	stats.TopUserAgents, err = r.getTopClickCounters(ctx, sqliteGetTopClickUserAgentsQuery, linkUID, from, to, query.Top)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select top user agents")
+	}
+
+	return stats, nil
+}
+
+func (r *SQLiteRepo) getTopClickCounters(ctx context.Context, query string, args ...any) ([]entity.ClickCounter, error) {
+	var counters []entity.ClickCounter
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var counter entity.ClickCounter
+		err := rows.Scan(&counter.Value, &counter.Count)
+		if err != nil {
+			return nil, err
+		}
+		counters = append(counters, counter)
+	}
+
+	return counters, rows.Err()
+}
+
+func (r *SQLiteRepo) EnqueueDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	linkUIDs, err := json.Marshal(task.LinkUIDs)
+	if err != nil {
+		return r.log.Wrap(err, "marshal link uids")
+	}
+
+	row := r.db.QueryRowContext(ctx, sqliteEnqueueDeleteTaskQuery,
+		task.UserUID, string(linkUIDs), task.Attempts, task.NextAttemptAt.UnixMilli(), task.CreatedAt.UnixMilli())
+	err = row.Scan(&task.ID)
+	if err != nil {
+		return r.log.Wrap(err, "insert delete task")
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetDeleteTask(ctx context.Context, taskID int64) (*entity.DeleteTask, error) {
+	tasks, err := r.queryDeleteTasks(ctx, sqliteGetDeleteTaskQuery, taskID)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return tasks[0], nil
+}
+
+func (r *SQLiteRepo) GetDueDeleteTasks(ctx context.Context, now time.Time, limit int) ([]*entity.DeleteTask, error) {
+	return r.queryDeleteTasks(ctx, sqliteGetDueDeleteTasksQuery, now.UnixMilli(), limit)
+}
+
+func (r *SQLiteRepo) CountPendingDeleteTasks(ctx context.Context) (int64, error) {
+	var pending int64
+	err := r.db.QueryRowContext(ctx, sqliteCountPendingTasksQuery).Scan(&pending)
+	if err != nil {
+		return 0, r.log.Wrap(err, "count delete tasks")
+	}
+	return pending, nil
+}
+
+func (r *SQLiteRepo) CompleteDeleteTasks(ctx context.Context, tasks []*entity.DeleteTask) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.log.Wrap(err, "begin tx")
+	}
+
+	for _, task := range tasks {
+		notFound, err := marshalUIDs(task.NotFound)
+		if err != nil {
+			r.rollback(ctx, tx)
+			return r.log.Wrap(err, "marshal not found uids")
+		}
+		_, err = tx.ExecContext(ctx, sqliteCompleteDeleteTaskQuery,
+			task.Attempts, task.Deleted, string(notFound), toUnixMilli(task.DoneAt), task.ID)
+		if err != nil {
+			r.rollback(ctx, tx)
+			return r.log.Wrap(err, "update delete task")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return r.log.Wrap(err, "commit after update")
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) RetryDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	_, err := r.db.ExecContext(ctx, sqliteRetryDeleteTaskQuery, task.Attempts, task.NextAttemptAt.UnixMilli(), task.LastError, task.ID)
+	if err != nil {
+		return r.log.Wrap(err, "update delete task")
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeadLetterDeleteTask(ctx context.Context, task *entity.DeleteTask) error {
+	_, err := r.db.ExecContext(ctx, sqliteDeadLetterDeleteTaskQuery, task.Attempts, task.LastError, toUnixMilli(task.DeadAt), task.ID)
+	if err != nil {
+		return r.log.Wrap(err, "update delete task")
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetDeadDeleteTasks(ctx context.Context) ([]*entity.DeleteTask, error) {
+	return r.queryDeleteTasks(ctx, sqliteGetDeadDeleteTasksQuery)
+}
+
+func (r *SQLiteRepo) PurgeDeleteTasks(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, sqlitePurgeDeleteTasksQuery, before.UnixMilli())
+	if err != nil {
+		return 0, r.log.Wrap(err, "delete done tasks")
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.log.Wrap(err, "rows affected")
+	}
+	return purged, nil
+}
+
+func (r *SQLiteRepo) queryDeleteTasks(ctx context.Context, query string, args ...any) ([]*entity.DeleteTask, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, r.log.Wrap(err, "select delete tasks")
+	}
+	defer rows.Close()
+
+	var tasks []*entity.DeleteTask
+	for rows.Next() {
+		task := new(entity.DeleteTask)
+		var linkUIDs, notFound string
+		var nextAttemptAt, createdAt int64
+		var doneAt, deadAt sql.NullInt64
+
+		err := rows.Scan(&task.ID, &task.UserUID, &linkUIDs, &task.Attempts, &nextAttemptAt, &task.LastError,
+			&task.Deleted, &notFound, &createdAt, &doneAt, &deadAt)
+		if err != nil {
+			return nil, r.log.Wrap(err, "scan delete task")
+		}
+		err = json.Unmarshal([]byte(linkUIDs), &task.LinkUIDs)
+		if err != nil {
+			return nil, r.log.Wrap(err, "unmarshal link uids")
+		}
+		err = json.Unmarshal([]byte(notFound), &task.NotFound)
+		if err != nil {
+			return nil, r.log.Wrap(err, "unmarshal not found uids")
+		}
+		if len(task.NotFound) == 0 {
+			task.NotFound = nil
+		}
+		task.NextAttemptAt = time.UnixMilli(nextAttemptAt).UTC()
+		task.CreatedAt = time.UnixMilli(createdAt).UTC()
+		task.DoneAt = fromUnixMilli(doneAt)
+		task.DeadAt = fromUnixMilli(deadAt)
+
+		tasks = append(tasks, task)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, r.log.Wrap(err, "rows next")
+	}
+	return tasks, nil
+}
+
+// Backup streams all shortlinks (deleted ones included) with their revisions to the backup storage
+func (r *SQLiteRepo) Backup(ctx context.Context) error {
+	var count int
+
+	err := r.backup.BackupEach(ctx, func(yield func(link *entity.Shortlink) error) error {
+		return r.IterateShortlinks(ctx, func(link *entity.Shortlink) error {
+			count++
+			return yield(link)
+		})
+	})
+	if err != nil {
+		return r.log.Wrap(err, "backup")
+	}
+
+	r.log.Info(ctx).Msgf("Backed up %d shortlinks", count)
+	return nil
+}
+
+// Restore loads shortlinks from the backup storage, but only into an empty database,
+// so that a stale backup never overrides links changed since then
+func (r *SQLiteRepo) Restore(ctx context.Context) error {
+	existing, err := r.CountShortlinks(ctx)
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		r.log.Info(ctx).Msgf("Database already has %d shortlinks, restore skipped", existing)
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.log.Wrap(err, "begin tx")
+	}
+
+	var restored int64
+	batch := make([]*entity.Shortlink, 0, sqliteInsertBatchSize)
+
+	flush := func() error {
+		inserted, err := r.insertShortlinks(ctx, tx, batch)
+		if err != nil {
+			return err
+		}
+		restored += inserted
+		batch = batch[:0]
+		return nil
+	}
+
+	err = r.backup.RestoreEach(ctx, func(link *entity.Shortlink) error {
+		batch = append(batch, link)
+		if len(batch) < sqliteInsertBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err != nil {
+		r.rollback(ctx, tx)
+		return r.log.Wrap(err, "restore")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return r.log.Wrap(err, "commit after restore")
+	}
+
+	r.log.Info(ctx).Msgf("Restored %d shortlinks", restored)
+	return nil
+}
+
+func (r *SQLiteRepo) Close(ctx context.Context) error {
+	err := r.backup.Close(ctx)
+	if err != nil {
+		return r.log.Wrap(err, "close backup")
+	}
+
+	err = r.db.Close()
+	if err != nil {
+		return r.log.Wrap(err, "close db")
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepo) rollback(ctx context.Context, tx *sql.Tx) {
+	err := tx.Rollback()
+	if err != nil {
+		r.log.Error(ctx, err).Msg("rollback")
+	}
+}
+
+func scanSQLiteShortlink(row rowScanner, extra ...any) (*entity.Shortlink, error) {
+	link := new(entity.Shortlink)
+	var userUID, corrID sql.NullString
+	var createdAt, updatedAt int64
+	var expiresAt, deletedAt sql.NullInt64
+
+	dest := append([]any{&link.UID, &userUID, &link.Short, &link.Long, &link.Deleted, &corrID, &createdAt, &updatedAt, &expiresAt, &deletedAt}, extra...)
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	link.UserUID = userUID.String
+	link.CorrelationID = corrID.String
+	link.CreatedAt = time.UnixMilli(createdAt).UTC()
+	link.UpdatedAt = time.UnixMilli(updatedAt).UTC()
+	link.ExpiresAt = fromUnixMilli(expiresAt)
+	link.DeletedAt = fromUnixMilli(deletedAt)
+
+	return link, nil
+}
+
+func isSQLiteUIDConflict(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE &&
+		strings.Contains(sqliteErr.Error(), "shortlinks.link_uid")
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// marshalUIDs encodes no UIDs as an empty JSON array rather than null
+func marshalUIDs(uids []string) ([]byte, error) {
+	if uids == nil {
+		uids = []string{}
+	}
+	return json.Marshal(uids)
+}
+
+func toUnixMilli(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UnixMilli()
+}
+
+func fromUnixMilli(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := time.UnixMilli(ms.Int64).UTC()
+	return &t
+}
@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/storage"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestSQLiteRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := prepareSQLiteRepo(t)
+
+	link := &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org"}
+
+	saved, err := repo.SaveShortlink(ctx, link)
+	require.NoError(t, err)
+	assert.Equal(t, link.UID, saved.UID)
+
+	t.Run("long URL conflict returns existing link", func(t *testing.T) {
+		existing, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: "link2", UserUID: "user2", Short: "http://127.0.0.1/link2", Long: "https://example.org"})
+		assert.ErrorIs(t, err, ErrURLConflict)
+		require.NotNil(t, existing)
+		assert.Equal(t, "link1", existing.UID)
+	})
+
+	t.Run("link UID conflict", func(t *testing.T) {
+		_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: "link1", UserUID: "user2", Short: "http://127.0.0.1/link1", Long: "https://google.com"})
+		assert.ErrorIs(t, err, ErrUIDConflict)
+	})
+
+	t.Run("find by user", func(t *testing.T) {
+		found, err := repo.FindShortlink(ctx, "user1", "link1")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, "https://example.org", found.Long)
+
+		found, err = repo.FindShortlink(ctx, "user2", "link1")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("soft delete", func(t *testing.T) {
+		_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: "link3", UserUID: "user1", Short: "http://127.0.0.1/link3", Long: "https://google.com"})
+		require.NoError(t, err)
+
+		deleted, err := repo.DeleteShortlinks(ctx, "user1", []string{"link3", "unknown"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"link3"}, deleted)
+
+		// Already deleted
+		deleted, err = repo.DeleteShortlinks(ctx, "user1", []string{"link3"})
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+
+		found, err := repo.FindShortlink(ctx, "", "link3")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.True(t, found.Deleted)
+		assert.False(t, found.UpdatedAt.IsZero())
+
+		page, err := repo.GetShortlinks(ctx, "user1", entity.ShortlinkQuery{})
+		require.NoError(t, err)
+		assert.Len(t, page.Links, 1)
+
+		trash, err := repo.GetDeletedShortlinks(ctx, "user1")
+		require.NoError(t, err)
+		require.Len(t, trash, 1)
+		assert.Equal(t, "link3", trash[0].UID)
+		assert.NotNil(t, trash[0].DeletedAt)
+	})
+
+	t.Run("undelete", func(t *testing.T) {
+		// Not owned by user2
+		restored, err := repo.UndeleteShortlinks(ctx, "user2", []string{"link3"})
+		require.NoError(t, err)
+		assert.Empty(t, restored)
+		found, err := repo.FindShortlink(ctx, "user1", "link3")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		restored, err = repo.UndeleteShortlinks(ctx, "user1", []string{"link3", "link1"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"link3"}, restored)
+		found, err = repo.FindShortlink(ctx, "user1", "link3")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.False(t, found.Deleted)
+		assert.Nil(t, found.DeletedAt)
+	})
+
+	t.Run("retarget", func(t *testing.T) {
+		updated, err := repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.net")
+		require.NoError(t, err)
+		require.NotNil(t, updated)
+		assert.Equal(t, "https://example.net", updated.Long)
+		assert.False(t, updated.UpdatedAt.IsZero())
+
+		// Same URL is not a new revision
+		_, err = repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.net")
+		require.NoError(t, err)
+
+		existing, err := repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://google.com")
+		assert.ErrorIs(t, err, ErrURLConflict)
+		require.NotNil(t, existing)
+		assert.Equal(t, "link3", existing.UID)
+
+		updated, err = repo.UpdateShortlinkLong(ctx, "user2", "link1", "https://ya.ru")
+		require.NoError(t, err)
+		assert.Nil(t, updated)
+
+		updated, err = repo.UpdateShortlinkLong(ctx, "user1", "link1", "https://example.org/new")
+		require.NoError(t, err)
+		require.NotNil(t, updated)
+
+		revisions, err := repo.GetShortlinkRevisions(ctx, "link1")
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, "https://example.net", revisions[0].Long)
+		assert.Equal(t, "https://example.org", revisions[1].Long)
+	})
+
+	t.Run("import and iterate", func(t *testing.T) {
+		imported, err := repo.ImportShortlinks(ctx, []*entity.Shortlink{
+			{UID: "link1", UserUID: "user3", Short: "http://127.0.0.1/link1", Long: "https://example.com"},
+			{UID: "link5", UserUID: "user3", Short: "http://127.0.0.1/link5", Long: "https://example.com", Deleted: true, CorrelationID: "corr5"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), imported)
+
+		var links []*entity.Shortlink
+		err = repo.IterateShortlinks(ctx, func(link *entity.Shortlink) error {
+			links = append(links, link)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, links, 3)
+
+		count, err := repo.CountShortlinks(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.Equal(t, &entity.Shortlink{UID: "link5", UserUID: "user3", Short: "http://127.0.0.1/link5", Long: "https://example.com", Deleted: true, CorrelationID: "corr5"}, links[2])
+	})
+
+	t.Run("delete expired", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Minute)
+		_, err := repo.SaveShortlink(ctx, &entity.Shortlink{UID: "link4", UserUID: "user1", Short: "http://127.0.0.1/link4", Long: "https://ya.ru", ExpiresAt: &expiresAt})
+		require.NoError(t, err)
+
+		deleted, err := repo.DeleteExpiredShortlinks(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("purge trash", func(t *testing.T) {
+		_, err := repo.DeleteShortlinks(ctx, "user1", []string{"link3"})
+		require.NoError(t, err)
+
+		purged, err := repo.PurgeDeletedShortlinks(ctx, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+
+		purged, err = repo.PurgeDeletedShortlinks(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		found, err := repo.FindShortlink(ctx, "", "link3")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("click stats", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Hour)
+		err := repo.SaveClicks(ctx, []*entity.Click{
+			{LinkUID: "link1", Timestamp: now.Add(-90 * time.Minute), Referrer: "https://t.me", UserAgent: "curl", IP: "10.0.0.0"},
+			{LinkUID: "link1", Timestamp: now.Add(-30 * time.Minute), Referrer: "https://t.me", UserAgent: "firefox", IP: "10.0.0.0"},
+		})
+		require.NoError(t, err)
+
+		stats, err := repo.GetClickStats(ctx, "link1", entity.ClickStatsQuery{
+			From:   now.Add(-2 * time.Hour),
+			To:     now,
+			Bucket: entity.ClickBucketHour,
+			Top:    10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), stats.Total)
+		assert.Equal(t, int64(2), stats.UniqueVisitors)
+		require.Len(t, stats.Buckets, 2)
+		assert.Equal(t, now.Add(-2*time.Hour), stats.Buckets[0].Start)
+		require.Len(t, stats.TopReferrers, 1)
+		assert.Equal(t, int64(2), stats.TopReferrers[0].Count)
+	})
+
+	t.Run("delete queue", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Millisecond)
+
+		first := &entity.DeleteTask{UserUID: "user1", LinkUIDs: []string{"link1"}, NextAttemptAt: now, CreatedAt: now}
+		second := &entity.DeleteTask{UserUID: "user2", LinkUIDs: []string{"link2", "link5"}, NextAttemptAt: now, CreatedAt: now}
+		require.NoError(t, repo.EnqueueDeleteTask(ctx, first))
+		require.NoError(t, repo.EnqueueDeleteTask(ctx, second))
+		assert.NotZero(t, first.ID)
+		assert.Greater(t, second.ID, first.ID)
+
+		due, err := repo.GetDueDeleteTasks(ctx, now, 10)
+		require.NoError(t, err)
+		require.Len(t, due, 2)
+		assert.Equal(t, second, due[1])
+
+		first.Attempts = 1
+		first.NextAttemptAt = now.Add(time.Minute)
+		first.LastError = "connection reset by peer"
+		require.NoError(t, repo.RetryDeleteTask(ctx, first))
+
+		due, err = repo.GetDueDeleteTasks(ctx, now, 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, second.ID, due[0].ID)
+
+		deadAt := now
+		second.Attempts = 10
+		second.DeadAt = &deadAt
+		require.NoError(t, repo.DeadLetterDeleteTask(ctx, second))
+
+		pending, err := repo.CountPendingDeleteTasks(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), pending)
+
+		dead, err := repo.GetDeadDeleteTasks(ctx)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, second, dead[0])
+
+		first.Attempts = 2
+		first.Deleted = 0
+		first.NotFound = []string{"link1"}
+		first.DoneAt = &now
+		require.NoError(t, repo.CompleteDeleteTasks(ctx, []*entity.DeleteTask{first}))
+		due, err = repo.GetDueDeleteTasks(ctx, now.Add(time.Hour), 10)
+		require.NoError(t, err)
+		assert.Empty(t, due)
+
+		done, err := repo.GetDeleteTask(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first, done)
+
+		purged, err := repo.PurgeDeleteTasks(ctx, now.Add(time.Millisecond))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		done, err = repo.GetDeleteTask(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Nil(t, done)
+	})
+}
+
+func TestSQLiteListShortlinks(t *testing.T) {
+	testListShortlinks(t, prepareSQLiteRepo(t))
+}
+
+func TestSQLiteBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	backupPath := filepath.Join(t.TempDir(), "backup.json")
+
+	backup, err := storage.NewFileStorage(backupPath)
+	require.NoError(t, err)
+	repo := openSQLiteRepo(t, backup)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	links := []*entity.Shortlink{
+		{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org", CreatedAt: now, UpdatedAt: now, Revisions: []*entity.ShortlinkRevision{
+			{LinkUID: "link1", Long: "https://example.net", ReplacedAt: now.Add(-time.Hour)},
+			{LinkUID: "link1", Long: "https://ya.ru", ReplacedAt: now},
+		}},
+		{UID: "link2", UserUID: "user1", Short: "http://127.0.0.1/link2", Long: "https://example.com", Deleted: true, CorrelationID: "corr2", CreatedAt: now, UpdatedAt: now, DeletedAt: &now},
+	}
+	_, err = repo.ImportShortlinks(ctx, links)
+	require.NoError(t, err)
+	require.NoError(t, repo.Backup(ctx))
+	require.NoError(t, repo.Close(ctx))
+
+	backup, err = storage.NewFileStorage(backupPath)
+	require.NoError(t, err)
+	restored := openSQLiteRepo(t, backup)
+	require.NoError(t, restored.Restore(ctx))
+
+	var got []*entity.Shortlink
+	err = restored.IterateShortlinks(ctx, func(link *entity.Shortlink) error {
+		got = append(got, link)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, links, got)
+
+	revisions, err := restored.GetShortlinkRevisions(ctx, "link1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "https://ya.ru", revisions[0].Long)
+
+	// The database is not empty anymore, a second restore must not touch it
+	_, err = restored.DeleteShortlinks(ctx, "user1", []string{"link1"})
+	require.NoError(t, err)
+	require.NoError(t, restored.Restore(ctx))
+
+	found, err := restored.FindShortlink(ctx, "", "link1")
+	require.NoError(t, err)
+	assert.True(t, found.Deleted)
+}
+
+func prepareSQLiteRepo(t *testing.T) *SQLiteRepo {
+	backup, err := storage.NewFileStorage("")
+	require.NoError(t, err)
+
+	return openSQLiteRepo(t, backup)
+}
+
+func openSQLiteRepo(t *testing.T, backup storage.Storage) *SQLiteRepo {
+	repo, err := NewSQLiteRepo(context.Background(), config.SQLite{
+		Path:           filepath.Join(t.TempDir(), "shortener.db"),
+		MigrationsPath: "../../../migrations/sqlite",
+	}, backup, logger.NewMockLogger())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = repo.Close(context.Background())
+	})
+
+	return repo
+}
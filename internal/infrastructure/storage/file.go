@@ -1,14 +1,20 @@
 package storage
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"os"
+	"time"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 )
 
+// FileStorage keeps a snapshot of all links as a single JSON array
 type FileStorage struct {
+	path string
 	file *os.File
 }
 
@@ -23,40 +29,139 @@ func NewFileStorage(filepath string) (*FileStorage, error) {
 	}
 
 	return &FileStorage{
+		path: filepath,
 		file: file,
 	}, nil
 }
 
+func (fs *FileStorage) Ping(ctx context.Context) error {
+	if fs.file == nil {
+		return nil
+	}
+	_, err := os.Stat(fs.path)
+	return err
+}
+
+func (fs *FileStorage) AppendSaved(ctx context.Context, links []*entity.Shortlink) error {
+	return nil
+}
+
+func (fs *FileStorage) AppendDeleted(ctx context.Context, userUID string, linkUIDs []string) error {
+	return nil
+}
+
+func (fs *FileStorage) AppendRevised(ctx context.Context, link *entity.Shortlink, revision *entity.ShortlinkRevision) error {
+	return nil
+}
+
 func (fs *FileStorage) Backup(ctx context.Context, links []*entity.Shortlink) error {
+	return fs.BackupEach(ctx, SliceIterator(links))
+}
+
+// BackupEach writes the snapshot to a temporary file first, so that a failed backup keeps the previous one
+func (fs *FileStorage) BackupEach(ctx context.Context, links ShortlinkIterator) error {
 	if fs.file == nil {
 		return nil
 	}
 
-	// Reset file
-	err := fs.file.Truncate(0)
+	tmpPath := fs.path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = writeSnapshot(tmp, links)
 	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
 		return err
 	}
-	_, err = fs.file.Seek(0, 0)
+	err = tmp.Close()
 	if err != nil {
 		return err
 	}
 
-	encoder := json.NewEncoder(fs.file)
+	err = os.Rename(tmpPath, fs.path)
+	if err != nil {
+		return err
+	}
 
-	return encoder.Encode(links)
+	file, err := os.OpenFile(fs.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_ = fs.file.Close()
+	fs.file = file
+
+	return nil
 }
 
 func (fs *FileStorage) Restore(ctx context.Context) ([]*entity.Shortlink, error) {
 	var links []*entity.Shortlink
 
+	err := fs.RestoreEach(ctx, func(link *entity.Shortlink) error {
+		links = append(links, link)
+		return nil
+	})
+
+	return links, err
+}
+
+func (fs *FileStorage) RestoreEach(ctx context.Context, fn func(link *entity.Shortlink) error) error {
 	if fs.file == nil {
-		return links, nil
+		return nil
+	}
+
+	_, err := fs.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
 	}
 
-	decoder := json.NewDecoder(fs.file)
+	reader := bufio.NewReader(fs.file)
 
-	return links, decoder.Decode(&links)
+	// Left by FileJournal, when switching back from the journal mode.
+	// Unlike FileJournal, a torn entry fails the restore rather than loading only part of the links
+	first, err := firstByte(reader)
+	if err == nil && first == '{' {
+		links, err := replayJournal(reader)
+		if err != nil {
+			return err
+		}
+		return SliceIterator(links)(fn)
+	}
+
+	now := time.Now().UTC()
+
+	decoder := json.NewDecoder(reader)
+
+	// Opening bracket (or null, or nothing at all, if nothing was backed up yet)
+	token, err := decoder.Token()
+	if errors.Is(err, io.EOF) || (err == nil && token == nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return errors.New("snapshot is not a JSON array")
+	}
+
+	for decoder.More() {
+		link := new(entity.Shortlink)
+		err := decoder.Decode(link)
+		if err != nil {
+			return err
+		}
+		fillTimestamps(link, now)
+		err = fn(link)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
 }
 
 func (fs *FileStorage) Close(ctx context.Context) error {
@@ -65,3 +170,54 @@ func (fs *FileStorage) Close(ctx context.Context) error {
 	}
 	return fs.file.Close()
 }
+
+// fillTimestamps upgrades links backed up before timestamps were recorded,
+// their lifetime (e.g. trash retention) starts at restore
+func fillTimestamps(link *entity.Shortlink, now time.Time) {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = now
+	}
+	if link.UpdatedAt.IsZero() {
+		link.UpdatedAt = link.CreatedAt
+	}
+	if link.Deleted && link.DeletedAt == nil {
+		link.DeletedAt = &now
+	}
+}
+
+func writeSnapshot(file *os.File, links ShortlinkIterator) error {
+	writer := bufio.NewWriter(file)
+
+	// One link per line
+	separator := "[\n"
+	err := links(func(link *entity.Shortlink) error {
+		data, err := json.Marshal(link)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.WriteString(separator); err != nil {
+			return err
+		}
+		separator = ",\n"
+		_, err = writer.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if separator == "[\n" {
+		_, err = writer.WriteString("[]\n")
+	} else {
+		_, err = writer.WriteString("\n]\n")
+	}
+	if err != nil {
+		return err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestFileStorage(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	link1 := &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org", CorrelationID: "corr1", CreatedAt: now, UpdatedAt: now}
+	link2 := &entity.Shortlink{UID: "link2", UserUID: "user1", Short: "http://127.0.0.1/link2", Long: "https://google.com", Deleted: true, CreatedAt: now, UpdatedAt: now, DeletedAt: &now}
+
+	t.Run("streamed backup is restored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "backup.json")
+
+		fs, err := NewFileStorage(path)
+		require.NoError(t, err)
+		require.NoError(t, fs.BackupEach(ctx, SliceIterator([]*entity.Shortlink{link1, link2})))
+		require.NoError(t, fs.Close(ctx))
+
+		fs, err = NewFileStorage(path)
+		require.NoError(t, err)
+		defer fs.Close(ctx)
+
+		var restored []*entity.Shortlink
+		err = fs.RestoreEach(ctx, func(link *entity.Shortlink) error {
+			restored = append(restored, link)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link1, link2}, restored)
+	})
+
+	t.Run("failed backup keeps the previous one", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "backup.json")
+
+		fs, err := NewFileStorage(path)
+		require.NoError(t, err)
+		defer fs.Close(ctx)
+
+		require.NoError(t, fs.Backup(ctx, []*entity.Shortlink{link1}))
+
+		err = fs.BackupEach(ctx, func(yield func(link *entity.Shortlink) error) error {
+			if err := yield(link2); err != nil {
+				return err
+			}
+			return errors.New("connection lost")
+		})
+		require.Error(t, err)
+
+		links, err := fs.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link1}, links)
+
+		_, err = os.Stat(path + ".tmp")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("empty and legacy null snapshots", func(t *testing.T) {
+		for _, content := range []string{"", "null\n"} {
+			path := filepath.Join(t.TempDir(), "backup.json")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+			fs, err := NewFileStorage(path)
+			require.NoError(t, err)
+
+			links, err := fs.Restore(ctx)
+			assert.NoError(t, err)
+			assert.Empty(t, links)
+			require.NoError(t, fs.Close(ctx))
+		}
+	})
+	t.Run("legacy snapshot without timestamps", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "backup.json")
+		content := `[{"id":"link1","user_id":"user1","short":"http://127.0.0.1/link1","long":"https://example.org","deleted":false,"correlation_id":""},` +
+			`{"id":"link2","user_id":"user1","short":"http://127.0.0.1/link2","long":"https://google.com","deleted":true,"correlation_id":""}]`
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		fs, err := NewFileStorage(path)
+		require.NoError(t, err)
+		defer fs.Close(ctx)
+
+		before := time.Now()
+		links, err := fs.Restore(ctx)
+		require.NoError(t, err)
+		require.Len(t, links, 2)
+
+		// Timestamps start at restore
+		assert.False(t, links[0].CreatedAt.Before(before.Truncate(time.Second)))
+		assert.Equal(t, links[0].CreatedAt, links[0].UpdatedAt)
+		assert.Nil(t, links[0].DeletedAt)
+		require.NotNil(t, links[1].DeletedAt)
+		assert.Equal(t, links[1].CreatedAt, *links[1].DeletedAt)
+	})
+	t.Run("restores journal written by file journal", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1, link2}))
+		require.NoError(t, journal.AppendDeleted(ctx, "user1", []string{"link1"}))
+		require.NoError(t, journal.Close(ctx))
+
+		fs, err := NewFileStorage(cfg.Filepath)
+		require.NoError(t, err)
+		defer fs.Close(ctx)
+
+		links, err := fs.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link2}, links)
+	})
+	t.Run("corrupted journal fails the restore", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1, link2}))
+		require.NoError(t, journal.Close(ctx))
+
+		file, err := os.OpenFile(cfg.Filepath, os.O_APPEND|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"op":"save","link":{"id":"li`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		fs, err := NewFileStorage(cfg.Filepath)
+		require.NoError(t, err)
+		defer fs.Close(ctx)
+
+		links, err := fs.Restore(ctx)
+		assert.Error(t, err)
+		assert.Empty(t, links)
+	})
+}
@@ -6,8 +6,37 @@ import (
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 )
 
+// ShortlinkIterator calls yield for every link, stopping at the first error
+type ShortlinkIterator func(yield func(link *entity.Shortlink) error) error
+
 type Storage interface {
+	// AppendSaved and AppendDeleted record individual changes, storages that only keep snapshots may ignore them
+	AppendSaved(ctx context.Context, links []*entity.Shortlink) error
+	AppendDeleted(ctx context.Context, userUID string, linkUIDs []string) error
+	// AppendRevised records the retargeted link along with the long URL it replaced
+	AppendRevised(ctx context.Context, link *entity.Shortlink, revision *entity.ShortlinkRevision) error
+
 	Backup(ctx context.Context, links []*entity.Shortlink) error
 	Restore(ctx context.Context) ([]*entity.Shortlink, error)
+
+	// BackupEach and RestoreEach do the same as Backup and Restore, without holding all links in memory
+	BackupEach(ctx context.Context, links ShortlinkIterator) error
+	RestoreEach(ctx context.Context, fn func(link *entity.Shortlink) error) error
+
+	// Ping checks that the storage is still usable, e.g. that the backup file was not removed
+	Ping(ctx context.Context) error
+
 	Close(ctx context.Context) error
 }
+
+// SliceIterator iterates over already loaded links
+func SliceIterator(links []*entity.Shortlink) ShortlinkIterator {
+	return func(yield func(link *entity.Shortlink) error) error {
+		for _, link := range links {
+			if err := yield(link); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
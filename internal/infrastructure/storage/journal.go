@@ -0,0 +1,436 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+const (
+	journalOpSave   = "save"
+	journalOpDelete = "delete"
+	journalOpRevise = "revise"
+)
+
+var errTornJournalEntry = errors.New("torn journal entry")
+
+type (
+	// FileJournal is an append-only storage: every change is appended as a JSON line,
+	// Restore replays the lines, compaction rewrites them as a snapshot of save lines
+	FileJournal struct {
+		cfg  config.Storage
+		file *os.File
+		log  *logger.Logger
+
+		dirty bool
+		mutex sync.Mutex
+		stop  chan struct{}
+		wg    sync.WaitGroup
+	}
+	journalEntry struct {
+		Op       string                    `json:"op"`
+		Link     *entity.Shortlink         `json:"link,omitempty"`
+		UserUID  string                    `json:"user_id,omitempty"`
+		LinkUIDs []string                  `json:"ids,omitempty"`
+		Revision *entity.ShortlinkRevision `json:"revision,omitempty"`
+	}
+)
+
+func NewFileJournal(cfg config.Storage, log *logger.Logger) (*FileJournal, error) {
+	file, err := os.OpenFile(cfg.Filepath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	fj := &FileJournal{
+		cfg:  cfg,
+		file: file,
+		log:  log,
+		stop: make(chan struct{}),
+	}
+
+	// Start from a clean snapshot: converts a FileStorage snapshot into the journal format,
+	// and drops a torn last line, which would otherwise hide everything appended after it.
+	// A journal corrupted anywhere else is left as it is, and the journal fails to open
+	err = fj.compact()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if cfg.FsyncPolicy == config.FsyncPolicyInterval && cfg.FsyncInterval > 0 {
+		fj.wg.Add(1)
+		go fj.syncPeriodically(cfg.FsyncInterval)
+	}
+	if cfg.CompactInterval > 0 {
+		fj.wg.Add(1)
+		go fj.compactPeriodically(cfg.CompactInterval)
+	}
+
+	return fj, nil
+}
+
+func (fj *FileJournal) AppendSaved(ctx context.Context, links []*entity.Shortlink) error {
+	entries := make([]journalEntry, 0, len(links))
+	for _, link := range links {
+		entries = append(entries, journalEntry{Op: journalOpSave, Link: link})
+	}
+	return fj.append(entries...)
+}
+
+func (fj *FileJournal) AppendDeleted(ctx context.Context, userUID string, linkUIDs []string) error {
+	return fj.append(journalEntry{Op: journalOpDelete, UserUID: userUID, LinkUIDs: linkUIDs})
+}
+
+func (fj *FileJournal) AppendRevised(ctx context.Context, link *entity.Shortlink, revision *entity.ShortlinkRevision) error {
+	return fj.append(journalEntry{Op: journalOpSave, Link: link}, journalEntry{Op: journalOpRevise, Revision: revision})
+}
+
+// Backup rewrites the journal as a snapshot of the given links
+func (fj *FileJournal) Backup(ctx context.Context, links []*entity.Shortlink) error {
+	return fj.BackupEach(ctx, SliceIterator(links))
+}
+
+func (fj *FileJournal) BackupEach(ctx context.Context, links ShortlinkIterator) error {
+	fj.mutex.Lock()
+	defer fj.mutex.Unlock()
+
+	return fj.rewrite(links)
+}
+
+func (fj *FileJournal) Restore(ctx context.Context) ([]*entity.Shortlink, error) {
+	fj.mutex.Lock()
+	defer fj.mutex.Unlock()
+
+	links, err := fj.replay()
+	if errors.Is(err, errTornJournalEntry) {
+		fj.log.Error(ctx, err).Msg("decode last journal entry, ignoring it")
+		return links, nil
+	}
+	return links, err
+}
+
+// RestoreEach still replays the whole journal in memory, as later lines may delete earlier ones
+func (fj *FileJournal) RestoreEach(ctx context.Context, fn func(link *entity.Shortlink) error) error {
+	links, err := fj.Restore(ctx)
+	if err != nil {
+		return err
+	}
+	return SliceIterator(links)(fn)
+}
+
+// Ping checks both the open file (closed on shutdown) and the path (the file may have been removed)
+func (fj *FileJournal) Ping(ctx context.Context) error {
+	fj.mutex.Lock()
+	defer fj.mutex.Unlock()
+
+	_, err := fj.file.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(fj.cfg.Filepath)
+	return err
+}
+
+func (fj *FileJournal) Close(ctx context.Context) error {
+	close(fj.stop)
+	fj.wg.Wait()
+
+	fj.mutex.Lock()
+	defer fj.mutex.Unlock()
+
+	err := fj.file.Sync()
+	if err != nil {
+		return err
+	}
+	return fj.file.Close()
+}
+
+func (fj *FileJournal) append(entries ...journalEntry) error {
+	fj.mutex.Lock()
+	defer fj.mutex.Unlock()
+
+	// Write all entries at once, so that a batch is never half-written by a concurrent append
+	var buf []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	_, err := fj.file.Write(buf)
+	if err != nil {
+		return err
+	}
+
+	if fj.cfg.FsyncPolicy == config.FsyncPolicyAlways {
+		return fj.file.Sync()
+	}
+	fj.dirty = true
+	return nil
+}
+
+// replay reads the whole journal and folds it into the resulting set of links.
+// Snapshots written by FileStorage (a single JSON array) are also supported
+func (fj *FileJournal) replay() ([]*entity.Shortlink, error) {
+	_, err := fj.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(fj.file)
+
+	first, err := firstByte(reader)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if first == '[' {
+		var links []*entity.Shortlink
+		err = json.NewDecoder(reader).Decode(&links)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		for _, link := range links {
+			fillTimestamps(link, now)
+		}
+		return links, nil
+	}
+
+	return replayJournal(reader)
+}
+
+// replayJournal folds journal lines into the resulting set of links. If the last line fails to decode,
+// it is a torn write (e.g. crash mid-append): the links before it are returned with errTornJournalEntry.
+// A line that fails to decode anywhere else is corruption, and fails the replay
+func replayJournal(reader *bufio.Reader) ([]*entity.Shortlink, error) {
+	//        linkUID
+	state := make(map[string]*entity.Shortlink)
+	var order []string
+	var tornErr error
+
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				break
+			}
+			continue
+		}
+
+		var entry journalEntry
+		decodeErr := json.Unmarshal(line, &entry)
+		if decodeErr != nil {
+			if _, err := firstByte(reader); !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("journal line %d: %w", lineNum, decodeErr)
+			}
+			tornErr = fmt.Errorf("%w: line %d: %v", errTornJournalEntry, lineNum, decodeErr)
+			break
+		}
+
+		switch entry.Op {
+		case journalOpSave:
+			if entry.Link == nil {
+				continue
+			}
+			existing, ok := state[entry.Link.UID]
+			if !ok {
+				order = append(order, entry.Link.UID)
+			} else if len(entry.Link.Revisions) == 0 {
+				// Changes of the link do not repeat its history
+				entry.Link.Revisions = existing.Revisions
+			}
+			state[entry.Link.UID] = entry.Link
+		case journalOpRevise:
+			if entry.Revision == nil {
+				continue
+			}
+			if link, ok := state[entry.Revision.LinkUID]; ok {
+				link.Revisions = append(link.Revisions, entry.Revision)
+			}
+		case journalOpDelete:
+			for _, linkUID := range entry.LinkUIDs {
+				if link, ok := state[linkUID]; ok && link.UserUID == entry.UserUID {
+					delete(state, linkUID)
+				}
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	now := time.Now().UTC()
+	links := make([]*entity.Shortlink, 0, len(state))
+	for _, linkUID := range order {
+		if link, ok := state[linkUID]; ok {
+			fillTimestamps(link, now)
+			links = append(links, link)
+		}
+	}
+	return links, tornErr
+}
+
+// firstByte skips leading whitespace and returns the next byte without consuming it
+func firstByte(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\n' && b != '\r' && b != '\t' {
+			return b, reader.UnreadByte()
+		}
+	}
+}
+
+// rewrite atomically replaces the journal with a snapshot of the given links
+func (fj *FileJournal) rewrite(links ShortlinkIterator) error {
+	tmpPath := fj.cfg.Filepath + ".compact"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	err = links(func(link *entity.Shortlink) error {
+		return encoder.Encode(journalEntry{Op: journalOpSave, Link: link})
+	})
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, fj.cfg.Filepath); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(fj.cfg.Filepath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_ = fj.file.Close()
+	fj.file = file
+	fj.dirty = false
+
+	return nil
+}
+
+func (fj *FileJournal) compact() error {
+	fj.mutex.Lock()
+	defer fj.mutex.Unlock()
+
+	links, err := fj.replay()
+	if errors.Is(err, errTornJournalEntry) {
+		fj.log.Error(context.Background(), err).Msg("decode last journal entry, dropping it")
+		// Keep what is dropped, for a look by hand
+		err = fj.keepCorrupt()
+	}
+	if err != nil {
+		return err
+	}
+	return fj.rewrite(SliceIterator(links))
+}
+
+// keepCorrupt copies the journal as it is to <path>.corrupt
+func (fj *FileJournal) keepCorrupt() error {
+	_, err := fj.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	corrupt, err := os.OpenFile(fj.cfg.Filepath+".corrupt", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(corrupt, fj.file)
+	if err != nil {
+		_ = corrupt.Close()
+		return err
+	}
+	if err := corrupt.Sync(); err != nil {
+		_ = corrupt.Close()
+		return err
+	}
+	return corrupt.Close()
+}
+
+func (fj *FileJournal) syncPeriodically(interval time.Duration) {
+	defer fj.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fj.stop:
+			return
+		case <-ticker.C:
+			fj.mutex.Lock()
+			if fj.dirty {
+				if err := fj.file.Sync(); err != nil {
+					fj.log.Error(context.Background(), err).Msg("sync journal")
+				} else {
+					fj.dirty = false
+				}
+			}
+			fj.mutex.Unlock()
+		}
+	}
+}
+
+func (fj *FileJournal) compactPeriodically(interval time.Duration) {
+	defer fj.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fj.stop:
+			return
+		case <-ticker.C:
+			if err := fj.compact(); err != nil {
+				fj.log.Error(context.Background(), err).Msg("compact journal")
+			} else {
+				fj.log.Debug(context.Background()).Msg("journal compacted")
+			}
+		}
+	}
+}
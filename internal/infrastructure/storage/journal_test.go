@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestFileJournal(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	link1 := &entity.Shortlink{UID: "link1", UserUID: "user1", Short: "http://127.0.0.1/link1", Long: "https://example.org", CreatedAt: now, UpdatedAt: now}
+	link2 := &entity.Shortlink{UID: "link2", UserUID: "user1", Short: "http://127.0.0.1/link2", Long: "https://google.com", CreatedAt: now, UpdatedAt: now}
+	link3 := &entity.Shortlink{UID: "link3", UserUID: "user2", Short: "http://127.0.0.1/link3", Long: "https://yandex.ru", CreatedAt: now, UpdatedAt: now}
+
+	t.Run("replays saves and deletes", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1, link2}))
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link3}))
+		// Not owned by user2, must be ignored
+		require.NoError(t, journal.AppendDeleted(ctx, "user2", []string{"link1"}))
+		require.NoError(t, journal.AppendDeleted(ctx, "user1", []string{"link2"}))
+		// Simulate a crash: no Backup, just close the file
+		require.NoError(t, journal.Close(ctx))
+
+		journal, err = NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		defer journal.Close(ctx)
+
+		links, err := journal.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link1, link3}, links)
+	})
+
+	t.Run("compaction keeps the state", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		defer journal.Close(ctx)
+
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1, link2}))
+		require.NoError(t, journal.AppendDeleted(ctx, "user1", []string{"link1"}))
+		require.NoError(t, journal.compact())
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link3}))
+
+		links, err := journal.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link2, link3}, links)
+	})
+
+	t.Run("replays revisions", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1}))
+
+		later := now.Add(time.Hour)
+		revised := *link1
+		revised.Long = "https://example.com"
+		revised.UpdatedAt = later
+		revision := &entity.ShortlinkRevision{LinkUID: link1.UID, Long: link1.Long, ReplacedAt: later}
+		require.NoError(t, journal.AppendRevised(ctx, &revised, revision))
+		require.NoError(t, journal.Close(ctx))
+
+		journal, err = NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		defer journal.Close(ctx)
+
+		links, err := journal.Restore(ctx)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.Equal(t, revised.Long, links[0].Long)
+		assert.Equal(t, []*entity.ShortlinkRevision{revision}, links[0].Revisions)
+	})
+
+	t.Run("torn last line is ignored", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		defer func() {
+			_ = journal.Close(ctx)
+		}()
+
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1}))
+		_, err = journal.file.WriteString(`{"op":"save","link":{"id":"li`)
+		require.NoError(t, err)
+
+		links, err := journal.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link1}, links)
+
+		// Appends after a restart must not be hidden behind the torn line
+		require.NoError(t, journal.Close(ctx))
+		journal, err = NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link2}))
+
+		links, err = journal.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link1, link2}, links)
+	})
+
+	t.Run("torn last line is kept aside on compaction", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1}))
+		_, err = journal.file.WriteString(`{"op":"save","link":{"id":"li`)
+		require.NoError(t, err)
+		require.NoError(t, journal.Close(ctx))
+
+		original, err := os.ReadFile(cfg.Filepath)
+		require.NoError(t, err)
+
+		journal, err = NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		defer journal.Close(ctx)
+
+		corrupt, err := os.ReadFile(cfg.Filepath + ".corrupt")
+		require.NoError(t, err)
+		assert.Equal(t, original, corrupt)
+	})
+
+	t.Run("corrupted line in the middle fails the restore", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link1}))
+		_, err = journal.file.WriteString(`{"op":"save","link":{"id":"li` + "\n")
+		require.NoError(t, err)
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link2}))
+
+		_, err = journal.Restore(ctx)
+		assert.Error(t, err)
+		require.NoError(t, journal.Close(ctx))
+
+		original, err := os.ReadFile(cfg.Filepath)
+		require.NoError(t, err)
+
+		// The links after the corrupted line must not be compacted away
+		_, err = NewFileJournal(cfg, logger.NewMockLogger())
+		assert.Error(t, err)
+
+		after, err := os.ReadFile(cfg.Filepath)
+		require.NoError(t, err)
+		assert.Equal(t, original, after)
+	})
+
+	t.Run("restores snapshot written by file storage", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		snapshot, err := NewFileStorage(cfg.Filepath)
+		require.NoError(t, err)
+		require.NoError(t, snapshot.Backup(ctx, []*entity.Shortlink{link1, link2}))
+		require.NoError(t, snapshot.Close(ctx))
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		defer journal.Close(ctx)
+
+		require.NoError(t, journal.AppendSaved(ctx, []*entity.Shortlink{link3}))
+
+		links, err := journal.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*entity.Shortlink{link1, link2, link3}, links)
+	})
+
+	t.Run("empty journal", func(t *testing.T) {
+		cfg := prepareJournalConfig(t)
+
+		journal, err := NewFileJournal(cfg, logger.NewMockLogger())
+		require.NoError(t, err)
+		defer journal.Close(ctx)
+
+		links, err := journal.Restore(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, links)
+	})
+}
+
+func prepareJournalConfig(t *testing.T) config.Storage {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	t.Cleanup(func() {
+		_ = os.Remove(path)
+	})
+
+	return config.Storage{
+		Filepath:    path,
+		Mode:        config.FileStorageModeJournal,
+		FsyncPolicy: config.FsyncPolicyAlways,
+	}
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+)
+
+var (
+	ErrUnknownExporter       = errors.New("unknown tracing exporter")
+	ErrExporterMisconfigured = errors.New("tracing exporter is misconfigured")
+)
+
+// ExporterConstructor returns nil exporter if spans should not be exported at all
+type ExporterConstructor func(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, error)
+
+var (
+	exporters      = make(map[string]ExporterConstructor)
+	exportersMutex sync.RWMutex
+)
+
+func init() {
+	RegisterExporter(config.TracingExporterNone, newNoneExporter)
+	RegisterExporter(config.TracingExporterStdout, newStdoutExporter)
+	RegisterExporter(config.TracingExporterFile, newFileExporter)
+	RegisterExporter(config.TracingExporterOTLP, newOTLPExporter)
+}
+
+func RegisterExporter(name string, constructor ExporterConstructor) {
+	exportersMutex.Lock()
+	defer exportersMutex.Unlock()
+
+	exporters[name] = constructor
+}
+
+func Exporters() []string {
+	exportersMutex.RLock()
+	defer exportersMutex.RUnlock()
+
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewExporter(ctx context.Context, name string, cfg config.Tracing) (sdktrace.SpanExporter, error) {
+	exportersMutex.RLock()
+	constructor, ok := exporters[name]
+	exportersMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrUnknownExporter
+	}
+	return constructor(ctx, cfg)
+}
+
+func newNoneExporter(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, error) {
+	return nil, nil
+}
+
+func newStdoutExporter(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithPrettyPrint())
+}
+
+// newFileExporter writes spans as JSON lines, the file is closed by the exporter on shutdown
+func newFileExporter(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, error) {
+	if cfg.Filepath == "" {
+		return nil, ErrExporterMisconfigured
+	}
+
+	file, err := os.OpenFile(cfg.Filepath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &fileExporter{SpanExporter: exporter, file: file}, nil
+}
+
+func newOTLPExporter(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, error) {
+	if cfg.OTLPEndpoint == "" {
+		return nil, ErrExporterMisconfigured
+	}
+
+	// Both host:port and the URL form of OTEL_EXPORTER_OTLP_ENDPOINT are accepted
+	endpoint, insecure := cfg.OTLPEndpoint, cfg.OTLPInsecure
+	switch {
+	case strings.HasPrefix(endpoint, "http://"):
+		endpoint, insecure = strings.TrimPrefix(endpoint, "http://"), true
+	case strings.HasPrefix(endpoint, "https://"):
+		endpoint = strings.TrimPrefix(endpoint, "https://")
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
+)
+
+func TestPropagation(t *testing.T) {
+	ctx := context.Background()
+
+	provider, err := Init(ctx, config.Tracing{Exporter: config.TracingExporterNone, ServiceName: "test", SampleRatio: 1}, logger.NewMockLogger())
+	require.NoError(t, err)
+	defer provider.Shutdown(ctx)
+
+	t.Run("continues incoming trace", func(t *testing.T) {
+		in := http.Header{}
+		in.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		ctx, span := StartServer(ctx, "test", propagation.HeaderCarrier(in))
+		defer span.End()
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", TraceID(ctx))
+
+		out := http.Header{}
+		Inject(ctx, propagation.HeaderCarrier(out))
+		assert.True(t, strings.HasPrefix(out.Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+		assert.NotContains(t, out.Get("traceparent"), "00f067aa0ba902b7")
+	})
+
+	t.Run("starts new trace", func(t *testing.T) {
+		ctx, span := StartServer(ctx, "test", propagation.HeaderCarrier(http.Header{}))
+		defer span.End()
+		assert.Len(t, TraceID(ctx), 32)
+	})
+
+	t.Run("no trace", func(t *testing.T) {
+		assert.Empty(t, TraceID(ctx))
+	})
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		_, err := Init(ctx, config.Tracing{Exporter: "zipkin"}, logger.NewMockLogger())
+		assert.ErrorIs(t, err, ErrUnknownExporter)
+	})
+}
@@ -3,8 +3,19 @@ package usecase
 import "errors"
 
 var (
-	ErrInvalidURL    = errors.New("provided URL is invalid")
-	ErrIncompleteURL = errors.New("provided URL is incomplete (e.g. missing scheme or host)")
-	ErrUIDConflict   = errors.New("shortlink UID conflict")
-	ErrDBUnavailable = errors.New("database is unavailable")
+	ErrInvalidURL     = errors.New("provided URL is invalid")
+	ErrIncompleteURL  = errors.New("provided URL is incomplete (e.g. missing scheme or host)")
+	ErrUIDConflict    = errors.New("shortlink UID conflict")
+	ErrInvalidAlias   = errors.New("alias has invalid length or contains invalid characters")
+	ErrReservedAlias  = errors.New("alias is reserved")
+	ErrAliasConflict  = errors.New("alias is already taken")
+	ErrInvalidExpiry  = errors.New("provided expiry is invalid (must be either TTL or expiration time in the future)")
+	ErrDBUnavailable  = errors.New("database is unavailable")
+	ErrTooManyDeletes = errors.New("too many pending deletes, try again later")
+
+	ErrShortlinkNotFound = errors.New("shortlink not found")
+	ErrDeleteJobNotFound = errors.New("delete job not found")
+	ErrInvalidStatsQuery = errors.New("invalid stats query (check time range and bucket size)")
+	ErrInvalidListQuery  = errors.New("invalid list query (check sort order and cursor)")
+	ErrNothingToDelete   = errors.New("no shortlink IDs to delete")
 )
@@ -2,11 +2,20 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
 )
 
 type (
+	CreateShortlinkIn struct {
+		UserUID   string
+		Length    int
+		URL       string
+		Alias     string
+		ExpiresAt *time.Time
+		TTL       time.Duration
+	}
 	CreateShortlinksIn struct {
 		Links   []CreateShortlinksInLink
 		UserUID string
@@ -14,6 +23,9 @@ type (
 	}
 	CreateShortlinksInLink struct {
 		URL           string
+		Alias         string
+		ExpiresAt     *time.Time
+		TTL           time.Duration
 		CorrelationID string
 	}
 )
@@ -21,10 +33,18 @@ type (
 type Shortener interface {
 	Ping(ctx context.Context) error
 
-	CreateShortlink(ctx context.Context, userUID string, length int, url string) (*entity.Shortlink, error)
+	CreateShortlink(ctx context.Context, data CreateShortlinkIn) (*entity.Shortlink, error)
 	CreateShortlinks(ctx context.Context, data CreateShortlinksIn) ([]*entity.Shortlink, error)
 	GetShortlink(ctx context.Context, linkUID string) (*entity.Shortlink, error)
 	GetUserShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error)
-	ListUserShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error)
-	DeleteUserShortlinks(ctx context.Context, userUID string, linkUIDs []string) error
+	ListUserShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (*entity.ShortlinkPage, error)
+	RetargetUserShortlink(ctx context.Context, userUID, linkUID, url string) (*entity.Shortlink, error)
+	GetUserShortlinkHistory(ctx context.Context, userUID, linkUID string) ([]*entity.ShortlinkRevision, error)
+	DeleteUserShortlinks(ctx context.Context, userUID string, linkUIDs []string) (*entity.DeleteTask, error)
+	GetUserDeleteJob(ctx context.Context, userUID string, jobID int64) (*entity.DeleteTask, error)
+	ListUserTrash(ctx context.Context, userUID string) ([]*entity.Shortlink, error)
+	RestoreUserShortlinks(ctx context.Context, userUID string, linkUIDs []string) ([]string, error)
+
+	RegisterClick(ctx context.Context, click *entity.Click) error
+	GetUserShortlinkStats(ctx context.Context, userUID, linkUID string, query entity.ClickStatsQuery) (*entity.ClickStats, error)
 }
@@ -3,56 +3,75 @@ package usecase
 import (
 	"context"
 	"errors"
-	"math/rand"
+	"net"
 	neturl "net/url"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/config"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/entity"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/linkid"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/metrics"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/repository/batch"
+	"github.com/eridiumdev/yandex-praktikum-go-shortener/internal/infrastructure/tracing"
 	"github.com/eridiumdev/yandex-praktikum-go-shortener/pkg/logger"
 )
 
+const (
+	defaultStatsDays = 7
+	defaultStatsTop  = 10
+	maxStatsTop      = 100
+	maxStatsBuckets  = 24 * 31
+
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
 type ShortenerUC struct {
-	baseURL       string
-	defaultLength int
-	alphabet      []rune
+	baseURL      string
+	idGenerator  linkid.LinkIDGenerator
+	idLength     *linkid.AdaptiveLength
+	idMaxRetries int
+
+	aliasAlphabet   string
+	aliasMinLength  int
+	aliasMaxLength  int
+	reservedAliases []string
 
 	repo           repository.ShortlinkRepo
+	clickRepo      repository.ClickRepo
 	batchProcessor batch.ShortlinkBatchProcessor
 
-	rng *rand.Rand
 	log *logger.Logger
 }
 
-func NewShortener(cfg config.Shortener, repo repository.ShortlinkRepo, batchProcessor batch.ShortlinkBatchProcessor, log *logger.Logger) *ShortenerUC {
-	var alphabet []rune
+func NewShortener(cfg config.Shortener, idGenerator linkid.LinkIDGenerator, idLength *linkid.AdaptiveLength, repo repository.ShortlinkRepo, clickRepo repository.ClickRepo, batchProcessor batch.ShortlinkBatchProcessor, log *logger.Logger) *ShortenerUC {
+	return &ShortenerUC{
+		baseURL:      strings.TrimRight(cfg.BaseURL, "/") + "/",
+		idGenerator:  idGenerator,
+		idLength:     idLength,
+		idMaxRetries: cfg.IDMaxRetries,
 
-	for c := '0'; c < '9'; c++ {
-		alphabet = append(alphabet, c)
-	}
-	for c := 'A'; c < 'Z'; c++ {
-		alphabet = append(alphabet, c)
-	}
-	for c := 'a'; c < 'z'; c++ {
-		alphabet = append(alphabet, c)
-	}
+		aliasAlphabet:   cfg.AliasAlphabet,
+		aliasMinLength:  cfg.AliasMinLength,
+		aliasMaxLength:  cfg.AliasMaxLength,
+		reservedAliases: cfg.ReservedAliases,
 
-	return &ShortenerUC{
-		baseURL:        strings.TrimRight(cfg.BaseURL, "/") + "/",
-		defaultLength:  cfg.DefaultLength,
-		alphabet:       alphabet,
 		repo:           repo,
+		clickRepo:      clickRepo,
 		batchProcessor: batchProcessor,
-		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
 		log:            log,
 	}
 }
 
-func (uc *ShortenerUC) Ping(ctx context.Context) error {
-	err := uc.repo.Ping(ctx)
+func (uc *ShortenerUC) Ping(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.Ping")
+	defer func() { tracing.End(span, err) }()
+
+	err = uc.repo.Ping(ctx)
 	if err != nil {
 		uc.log.Info(ctx).Msgf("error pinging repo: %s", err)
 		return ErrDBUnavailable
@@ -60,59 +79,92 @@ func (uc *ShortenerUC) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (uc *ShortenerUC) CreateShortlink(ctx context.Context, userUID string, length int, longURL string) (*entity.Shortlink, error) {
-	err := uc.validateURL(ctx, longURL)
+func (uc *ShortenerUC) CreateShortlink(ctx context.Context, data CreateShortlinkIn) (_ *entity.Shortlink, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.CreateShortlink")
+	defer func() { tracing.End(span, err) }()
+
+	err = uc.validateURL(ctx, data.URL)
 	if err != nil {
 		return nil, err
 	}
 
-	if length <= 0 {
-		length = uc.defaultLength
+	expiresAt, err := uc.resolveExpiry(ctx, data.ExpiresAt, data.TTL)
+	if err != nil {
+		return nil, err
 	}
 
-	var linkUID string
+	// Only IDs of the default length tell how full its keyspace is
+	adaptive := data.Alias == "" && data.Length <= 0
 
-	for tries := 0; ; tries++ {
-		linkUID = uc.generateLinkUID(length)
-		exists, err := uc.repo.FindShortlink(ctx, userUID, linkUID)
+	if data.Alias != "" {
+		err = uc.validateAlias(ctx, data.Alias)
 		if err != nil {
 			return nil, err
 		}
-		if exists == nil {
-			break
-		}
-		if tries > 2 {
-			return nil, ErrUIDConflict
-		}
 	}
 
+	now := time.Now().UTC()
 	link := &entity.Shortlink{
-		UID:     linkUID,
-		UserUID: userUID,
-		Long:    longURL,
-		Short:   uc.baseURL + linkUID,
+		UID:       data.Alias,
+		UserUID:   data.UserUID,
+		Long:      data.URL,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: expiresAt,
 	}
 
-	uc.log.Info(ctx).Msgf("URL shortened: %s -> %s", link.Long, link.Short)
+	// Generated UIDs are not looked up before saving, a taken one fails the insert and is re-generated
+	for tries := 0; ; tries++ {
+		if data.Alias == "" {
+			length := data.Length
+			if adaptive {
+				length = uc.idLength.Length()
+			}
+			link.UID, err = uc.idGenerator.Generate(ctx, length)
+			if err != nil {
+				return nil, uc.log.Wrap(err, "generate link UID")
+			}
+		}
+		link.Short = uc.baseURL + link.UID
+
+		saved, err := uc.repo.SaveShortlink(ctx, link)
+		if adaptive && (err == nil || errors.Is(err, repository.ErrUIDConflict)) {
+			uc.idLength.Observe(ctx, err != nil)
+		}
 
-	link, err = uc.repo.SaveShortlink(ctx, link)
-	if err != nil {
-		if errors.Is(err, repository.ErrURLConflict) {
-			return link, err
+		switch {
+		case err == nil:
+			uc.log.Info(ctx).Msgf("URL shortened: %s -> %s", saved.Long, saved.Short)
+			return saved, nil
+		case errors.Is(err, repository.ErrURLConflict):
+			return saved, err
+		case errors.Is(err, repository.ErrUIDConflict) && data.Alias != "":
+			return nil, ErrAliasConflict
+		case errors.Is(err, repository.ErrUIDConflict) && tries < uc.idMaxRetries:
+			metrics.UIDGenerationRetries.Inc()
+		case errors.Is(err, repository.ErrUIDConflict):
+			metrics.UIDGenerationFailures.Inc()
+			return nil, ErrUIDConflict
+		default:
+			return nil, uc.log.Wrap(err, "save shortlink")
 		}
-		return nil, uc.log.Wrap(err, "save shortlink")
 	}
-
-	return link, nil
 }
 
-func (uc *ShortenerUC) CreateShortlinks(ctx context.Context, data CreateShortlinksIn) ([]*entity.Shortlink, error) {
-	if data.Length <= 0 {
-		data.Length = uc.defaultLength
+func (uc *ShortenerUC) CreateShortlinks(ctx context.Context, data CreateShortlinksIn) (_ []*entity.Shortlink, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.CreateShortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	adaptive := data.Length <= 0
+	if adaptive {
+		data.Length = uc.idLength.Length()
 	}
 
-	linkMap := make(map[string]*entity.Shortlink, len(data.Links))
-	linkUIDs := make([]string, 0)
+	// Links are saved in the order of the request, links without an alias get their UIDs once all the aliases
+	// are known, so that those are not generated
+	links := make([]*entity.Shortlink, 0, len(data.Links))
+	aliases := make([]string, 0)
+	generated := make([]*entity.Shortlink, 0)
 
 	for _, longLink := range data.Links {
 		err := uc.validateURL(ctx, longLink.URL)
@@ -120,65 +172,118 @@ func (uc *ShortenerUC) CreateShortlinks(ctx context.Context, data CreateShortlin
 			return nil, uc.log.Wrapf(err, "URL = %s", longLink.URL)
 		}
 
-		link, err := uc.prepareShortlink(longLink.URL, data.Length, data.UserUID, longLink.CorrelationID)
+		expiresAt, err := uc.resolveExpiry(ctx, longLink.ExpiresAt, longLink.TTL)
 		if err != nil {
-			return nil, uc.log.Wrap(err, "prepare shortlink")
+			return nil, uc.log.Wrapf(err, "URL = %s", longLink.URL)
 		}
-		linkMap[link.UID] = link
-		linkUIDs = append(linkUIDs, link.UID)
-	}
 
-	for tries := 0; ; tries++ {
-		if tries > 2 {
-			return nil, ErrUIDConflict
+		link := uc.buildShortlink(longLink.Alias, longLink.URL, data.UserUID, longLink.CorrelationID)
+		link.ExpiresAt = expiresAt
+		links = append(links, link)
+
+		if longLink.Alias == "" {
+			generated = append(generated, link)
+			continue
 		}
 
-		duplicates, err := uc.repo.FindShortlinks(ctx, linkUIDs)
+		err = uc.validateAlias(ctx, longLink.Alias)
 		if err != nil {
-			return nil, uc.log.Wrap(err, "find shortlinks")
+			return nil, uc.log.Wrapf(err, "alias = %s", longLink.Alias)
 		}
-		if len(duplicates) == 0 {
-			break
+		if slices.Contains(aliases, longLink.Alias) {
+			return nil, uc.log.Wrapf(ErrAliasConflict, "alias = %s", longLink.Alias)
 		}
+		aliases = append(aliases, longLink.Alias)
+	}
 
-		// Reset for next FindShortlinks (will only contain re-generated links)
-		linkUIDs = linkUIDs[:0]
-
-		for _, dup := range duplicates {
-			link, err := uc.prepareShortlink(dup.Long, data.Length, data.UserUID, dup.CorrelationID)
+	// As for a single link, generated UIDs are not looked up before saving. The batch is saved all or nothing,
+	// so if any UID is taken, all generated ones are re-generated
+	for tries := 0; ; tries++ {
+		taken := make(map[string]bool, len(links))
+		for _, alias := range aliases {
+			taken[alias] = true
+		}
+		for _, link := range generated {
+			link.UID, err = uc.generateBatchUID(ctx, data.Length, taken)
 			if err != nil {
-				return nil, uc.log.Wrap(err, "prepare shortlink (dup)")
+				return nil, uc.log.Wrap(err, "generate link UID")
 			}
-			linkUIDs = append(linkUIDs, link.UID)
-			linkMap[dup.UID] = link
+			link.Short = uc.baseURL + link.UID
+			taken[link.UID] = true
 		}
-	}
 
-	var links []*entity.Shortlink
+		saved, err := uc.repo.SaveShortlinks(ctx, links)
+		if err == nil {
+			if adaptive {
+				for range generated {
+					uc.idLength.Observe(ctx, false)
+				}
+			}
+			for _, link := range saved {
+				uc.log.Info(ctx).Msgf("URL shortened: %s -> %s", link.Long, link.Short)
+			}
+			return saved, nil
+		}
+		if !errors.Is(err, repository.ErrUIDConflict) {
+			return nil, uc.log.Wrap(err, "save shortlinks")
+		}
 
-	for _, link := range linkMap {
-		links = append(links, link)
-		uc.log.Info(ctx).Msgf("URL shortened: %s -> %s", link.Long, link.Short)
-	}
+		// Only aliases are looked up, and only once the batch has failed
+		if len(aliases) > 0 {
+			existing, err := uc.repo.FindShortlinks(ctx, aliases)
+			if err != nil {
+				return nil, uc.log.Wrap(err, "find aliased shortlinks")
+			}
+			if len(existing) > 0 {
+				return nil, uc.log.Wrapf(ErrAliasConflict, "alias = %s", existing[0].UID)
+			}
+		}
+		if len(generated) == 0 {
+			return nil, ErrAliasConflict
+		}
 
-	links, err := uc.repo.SaveShortlinks(ctx, links)
-	if err != nil {
-		return nil, uc.log.Wrap(err, "save shortlinks")
+		// The failed insert tells that a generated UID was taken, not which one
+		if adaptive {
+			uc.idLength.Observe(ctx, true)
+		}
+		if tries >= uc.idMaxRetries {
+			metrics.UIDGenerationFailures.Inc()
+			return nil, ErrUIDConflict
+		}
+		metrics.UIDGenerationRetries.Inc()
 	}
-
-	return links, nil
 }
 
-func (uc *ShortenerUC) prepareShortlink(longURL string, length int, userUID string, correlationID string) (*entity.Shortlink, error) {
-	linkUID := uc.generateLinkUID(length)
+// generateBatchUID generates a UID, re-generating it while taken by another link of the batch
+func (uc *ShortenerUC) generateBatchUID(ctx context.Context, length int, taken map[string]bool) (string, error) {
+	for tries := 0; ; tries++ {
+		linkUID, err := uc.idGenerator.Generate(ctx, length)
+		if err != nil {
+			return "", err
+		}
+		if !taken[linkUID] {
+			return linkUID, nil
+		}
+
+		if tries >= uc.idMaxRetries {
+			metrics.UIDGenerationFailures.Inc()
+			return "", ErrUIDConflict
+		}
+		metrics.UIDGenerationRetries.Inc()
+	}
+}
 
+func (uc *ShortenerUC) buildShortlink(linkUID string, longURL string, userUID string, correlationID string) *entity.Shortlink {
+	now := time.Now().UTC()
 	return &entity.Shortlink{
 		UID:           linkUID,
 		UserUID:       userUID,
 		Long:          longURL,
 		Short:         uc.baseURL + linkUID,
 		CorrelationID: correlationID,
-	}, nil
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
 }
 
 func (uc *ShortenerUC) validateURL(ctx context.Context, url string) error {
@@ -194,31 +299,275 @@ func (uc *ShortenerUC) validateURL(ctx context.Context, url string) error {
 	return nil
 }
 
-func (uc *ShortenerUC) generateLinkUID(length int) string {
-	var id string
-
-	for i := 0; i < length; i++ {
-		random := uc.rng.Intn(len(uc.alphabet))
-		id += string(uc.alphabet[random])
+func (uc *ShortenerUC) resolveExpiry(ctx context.Context, expiresAt *time.Time, ttl time.Duration) (*time.Time, error) {
+	now := time.Now()
+
+	switch {
+	case expiresAt != nil && ttl != 0:
+		uc.log.Info(ctx).Msg("Both expiration time and TTL are provided")
+		return nil, ErrInvalidExpiry
+	case ttl < 0:
+		uc.log.Info(ctx).Msgf("Provided TTL is negative (%s)", ttl)
+		return nil, ErrInvalidExpiry
+	case ttl > 0:
+		expiry := now.Add(ttl)
+		return &expiry, nil
+	case expiresAt != nil && !expiresAt.After(now):
+		uc.log.Info(ctx).Msgf("Provided expiration time is in the past (%s)", expiresAt)
+		return nil, ErrInvalidExpiry
 	}
+	return expiresAt, nil
+}
 
-	return id
+func (uc *ShortenerUC) validateAlias(ctx context.Context, alias string) error {
+	length := utf8.RuneCountInString(alias)
+	if length < uc.aliasMinLength || length > uc.aliasMaxLength {
+		uc.log.Info(ctx).Msgf("Provided alias has invalid length (%s)", alias)
+		return ErrInvalidAlias
+	}
+	for _, c := range alias {
+		if !strings.ContainsRune(uc.aliasAlphabet, c) {
+			uc.log.Info(ctx).Msgf("Provided alias contains invalid characters (%s)", alias)
+			return ErrInvalidAlias
+		}
+	}
+	for _, reserved := range uc.reservedAliases {
+		if strings.EqualFold(alias, reserved) {
+			uc.log.Info(ctx).Msgf("Provided alias is reserved (%s)", alias)
+			return ErrReservedAlias
+		}
+	}
+	return nil
 }
 
-func (uc *ShortenerUC) GetShortlink(ctx context.Context, linkUID string) (*entity.Shortlink, error) {
+func (uc *ShortenerUC) GetShortlink(ctx context.Context, linkUID string) (_ *entity.Shortlink, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.GetShortlink")
+	defer func() { tracing.End(span, err) }()
+
 	return uc.repo.FindShortlink(ctx, "", linkUID)
 }
 
-func (uc *ShortenerUC) GetUserShortlink(ctx context.Context, userUID, linkUID string) (*entity.Shortlink, error) {
+func (uc *ShortenerUC) GetUserShortlink(ctx context.Context, userUID, linkUID string) (_ *entity.Shortlink, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.GetUserShortlink")
+	defer func() { tracing.End(span, err) }()
+
 	return uc.repo.FindShortlink(ctx, userUID, linkUID)
 }
 
-func (uc *ShortenerUC) ListUserShortlinks(ctx context.Context, userUID string) ([]*entity.Shortlink, error) {
-	return uc.repo.GetShortlinks(ctx, userUID)
+func (uc *ShortenerUC) ListUserShortlinks(ctx context.Context, userUID string, query entity.ShortlinkQuery) (_ *entity.ShortlinkPage, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.ListUserShortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	query, err = uc.normalizeListQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.repo.GetShortlinks(ctx, userUID, query)
 }
 
-func (uc *ShortenerUC) DeleteUserShortlinks(ctx context.Context, userUID string, linkUIDs []string) error {
-	go uc.batchProcessor.BatchDeleteShortlinks(context.WithoutCancel(ctx), userUID, linkUIDs)
+func (uc *ShortenerUC) normalizeListQuery(ctx context.Context, query entity.ShortlinkQuery) (entity.ShortlinkQuery, error) {
+	if query.Sort == "" {
+		query.Sort = entity.ShortlinkSortCreated
+	}
+	// Paging is opt-in, clients that do not know about it still get all their links
+	if query.Limit <= 0 && query.After != nil {
+		query.Limit = defaultListLimit
+	}
+	query.Domain = strings.ToLower(strings.TrimSpace(query.Domain))
+
+	if query.Sort != entity.ShortlinkSortCreated && query.Sort != entity.ShortlinkSortLong {
+		uc.log.Info(ctx).Msgf("Unknown list sort (%s)", query.Sort)
+		return query, ErrInvalidListQuery
+	}
+	// Cursor is a position in a specific order, it cannot continue a different one
+	if query.After != nil && (query.After.Sort != query.Sort || query.After.Desc != query.Desc) {
+		uc.log.Info(ctx).Msgf("Cursor does not match the list order (%s)", query.Sort)
+		return query, ErrInvalidListQuery
+	}
+	if query.Limit > maxListLimit {
+		query.Limit = maxListLimit
+	}
+
+	return query, nil
+}
+
+// RetargetUserShortlink changes the long URL of the user's link, keeping the short one.
+// The previous long URL is kept in the link history
+func (uc *ShortenerUC) RetargetUserShortlink(ctx context.Context, userUID, linkUID, url string) (_ *entity.Shortlink, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.RetargetUserShortlink")
+	defer func() { tracing.End(span, err) }()
+
+	err = uc.validateURL(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := uc.repo.UpdateShortlinkLong(ctx, userUID, linkUID, url)
+	if err != nil {
+		if errors.Is(err, repository.ErrURLConflict) {
+			return link, err
+		}
+		return nil, uc.log.Wrap(err, "update shortlink long")
+	}
+	if link == nil {
+		return nil, ErrShortlinkNotFound
+	}
+
+	uc.log.Info(ctx).Msgf("URL retargeted: %s -> %s", link.Short, link.Long)
+
+	return link, nil
+}
+
+func (uc *ShortenerUC) GetUserShortlinkHistory(ctx context.Context, userUID, linkUID string) (_ []*entity.ShortlinkRevision, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.GetUserShortlinkHistory")
+	defer func() { tracing.End(span, err) }()
+
+	link, err := uc.GetUserShortlink(ctx, userUID, linkUID)
+	if err != nil {
+		return nil, uc.log.Wrap(err, "get user shortlink")
+	}
+	if link == nil {
+		return nil, ErrShortlinkNotFound
+	}
+
+	return uc.repo.GetShortlinkRevisions(ctx, linkUID)
+}
+
+// DeleteUserShortlinks queues the delete, the returned task is the job to poll with GetUserDeleteJob
+func (uc *ShortenerUC) DeleteUserShortlinks(ctx context.Context, userUID string, linkUIDs []string) (_ *entity.DeleteTask, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.DeleteUserShortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	if len(linkUIDs) == 0 {
+		uc.log.Info(ctx).Msg("No shortlink IDs to delete")
+		return nil, ErrNothingToDelete
+	}
+
+	task, err := uc.batchProcessor.BatchDeleteShortlinks(ctx, userUID, linkUIDs)
+	if errors.Is(err, batch.ErrDeleteQueueFull) {
+		return nil, ErrTooManyDeletes
+	}
+	return task, err
+}
+
+func (uc *ShortenerUC) ListUserTrash(ctx context.Context, userUID string) (_ []*entity.Shortlink, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.ListUserTrash")
+	defer func() { tracing.End(span, err) }()
+
+	return uc.repo.GetDeletedShortlinks(ctx, userUID)
+}
+
+// RestoreUserShortlinks returns UIDs of the restored links, others are not in the user's trash
+func (uc *ShortenerUC) RestoreUserShortlinks(ctx context.Context, userUID string, linkUIDs []string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.RestoreUserShortlinks")
+	defer func() { tracing.End(span, err) }()
+
+	return uc.repo.UndeleteShortlinks(ctx, userUID, linkUIDs)
+}
+
+func (uc *ShortenerUC) GetUserDeleteJob(ctx context.Context, userUID string, jobID int64) (_ *entity.DeleteTask, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.GetUserDeleteJob")
+	defer func() { tracing.End(span, err) }()
+
+	task, err := uc.batchProcessor.GetDeleteTask(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	// Jobs of other users are not revealed
+	if task == nil || task.UserUID != userUID {
+		return nil, ErrDeleteJobNotFound
+	}
+	return task, nil
+}
+
+func (uc *ShortenerUC) RegisterClick(ctx context.Context, click *entity.Click) error {
+	click.IP = anonymizeIP(click.IP)
+	uc.batchProcessor.BatchSaveClick(ctx, click)
 
 	return nil
 }
+
+func (uc *ShortenerUC) GetUserShortlinkStats(ctx context.Context, userUID, linkUID string, query entity.ClickStatsQuery) (_ *entity.ClickStats, err error) {
+	ctx, span := tracing.Start(ctx, "ShortenerUC.GetUserShortlinkStats")
+	defer func() { tracing.End(span, err) }()
+
+	link, err := uc.GetUserShortlink(ctx, userUID, linkUID)
+	if err != nil {
+		return nil, uc.log.Wrap(err, "get user shortlink")
+	}
+	if link == nil {
+		return nil, ErrShortlinkNotFound
+	}
+
+	query, err = uc.normalizeStatsQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := uc.clickRepo.GetClickStats(ctx, linkUID, query)
+	if err != nil {
+		return nil, uc.log.Wrap(err, "get click stats")
+	}
+
+	// Fill in the gaps, so that every bucket in the range is present
+	counts := make(map[time.Time]int64, len(stats.Buckets))
+	for _, bucket := range stats.Buckets {
+		counts[bucket.Start] = bucket.Count
+	}
+	stats.Buckets = stats.Buckets[:0]
+	for start := query.BucketStart(query.From); start.Before(query.To); start = query.NextBucket(start) {
+		stats.Buckets = append(stats.Buckets, entity.ClickBucket{Start: start, Count: counts[start]})
+	}
+
+	return stats, nil
+}
+
+func (uc *ShortenerUC) normalizeStatsQuery(ctx context.Context, query entity.ClickStatsQuery) (entity.ClickStatsQuery, error) {
+	if query.Bucket == "" {
+		query.Bucket = entity.ClickBucketDay
+	}
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.AddDate(0, 0, -defaultStatsDays)
+	}
+	if query.Top <= 0 {
+		query.Top = defaultStatsTop
+	}
+
+	if query.Bucket != entity.ClickBucketHour && query.Bucket != entity.ClickBucketDay {
+		uc.log.Info(ctx).Msgf("Unknown stats bucket (%s)", query.Bucket)
+		return query, ErrInvalidStatsQuery
+	}
+	if !query.From.Before(query.To) {
+		uc.log.Info(ctx).Msgf("Stats range is empty (%s - %s)", query.From, query.To)
+		return query, ErrInvalidStatsQuery
+	}
+	bucketSize := time.Hour
+	if query.Bucket == entity.ClickBucketDay {
+		bucketSize = 24 * time.Hour
+	}
+	if query.To.Sub(query.BucketStart(query.From)) > maxStatsBuckets*bucketSize {
+		uc.log.Info(ctx).Msgf("Stats range is too wide (%s - %s)", query.From, query.To)
+		return query, ErrInvalidStatsQuery
+	}
+	if query.Top > maxStatsTop {
+		query.Top = maxStatsTop
+	}
+
+	return query, nil
+}
+
+// anonymizeIP truncates IPv4 addresses to /24 and IPv6 addresses to /48
+func anonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
@@ -0,0 +1,10 @@
+-- Aliases longer than the old columns cannot be kept, they have to be removed by hand before going down
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM shortlinks WHERE length(link_uid) > 8 OR length(short) > 32) THEN
+        RAISE EXCEPTION 'shortlinks with link_uid longer than 8 or short longer than 32 characters exist';
+    END IF;
+END $$;
+
+ALTER TABLE shortlinks ALTER COLUMN short TYPE VARCHAR (32);
+ALTER TABLE shortlinks ALTER COLUMN link_uid TYPE VARCHAR (8);
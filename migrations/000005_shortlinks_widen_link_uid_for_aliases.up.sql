@@ -0,0 +1,2 @@
+ALTER TABLE shortlinks ALTER COLUMN link_uid TYPE VARCHAR (64);
+ALTER TABLE shortlinks ALTER COLUMN short TYPE VARCHAR (512);
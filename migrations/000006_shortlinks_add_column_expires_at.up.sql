@@ -0,0 +1,2 @@
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
+CREATE INDEX IF NOT EXISTS IX_shortlinks_expires_at ON shortlinks (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS delete_outbox(
+   id bigserial PRIMARY KEY,
+   user_uid VARCHAR (32) NOT NULL,
+   link_uids JSONB NOT NULL,
+   attempts INTEGER NOT NULL DEFAULT 0,
+   next_attempt_at TIMESTAMPTZ NOT NULL,
+   last_error TEXT NOT NULL DEFAULT '',
+   created_at TIMESTAMPTZ NOT NULL,
+   dead_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS IX_delete_outbox_next_attempt_at ON delete_outbox (next_attempt_at) WHERE dead_at IS NULL;
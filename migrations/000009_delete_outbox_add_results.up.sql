@@ -0,0 +1,6 @@
+ALTER TABLE delete_outbox ADD COLUMN IF NOT EXISTS deleted_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE delete_outbox ADD COLUMN IF NOT EXISTS not_found JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE delete_outbox ADD COLUMN IF NOT EXISTS done_at TIMESTAMPTZ NULL;
+DROP INDEX IF EXISTS IX_delete_outbox_next_attempt_at;
+CREATE INDEX IF NOT EXISTS IX_delete_outbox_next_attempt_at ON delete_outbox (next_attempt_at) WHERE dead_at IS NULL AND done_at IS NULL;
+CREATE INDEX IF NOT EXISTS IX_delete_outbox_done_at ON delete_outbox (done_at) WHERE done_at IS NOT NULL;
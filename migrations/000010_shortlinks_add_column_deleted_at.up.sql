@@ -0,0 +1,3 @@
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+UPDATE shortlinks SET deleted_at = now() WHERE deleted = true AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS IX_shortlinks_deleted_at ON shortlinks (deleted_at) WHERE deleted = true;
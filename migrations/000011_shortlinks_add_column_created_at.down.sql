@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS IX_shortlinks_user_uid_created_at;
+ALTER TABLE shortlinks DROP COLUMN IF EXISTS created_at;
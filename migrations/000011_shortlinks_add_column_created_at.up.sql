@@ -0,0 +1,2 @@
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX IF NOT EXISTS IX_shortlinks_user_uid_created_at ON shortlinks (user_uid, created_at, link_uid);
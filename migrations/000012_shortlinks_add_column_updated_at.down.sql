@@ -0,0 +1 @@
+ALTER TABLE shortlinks DROP COLUMN IF EXISTS updated_at;
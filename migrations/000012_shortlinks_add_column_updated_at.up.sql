@@ -0,0 +1,2 @@
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE shortlinks SET updated_at = greatest(created_at, deleted_at);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS shortlink_revisions(
+   id bigserial PRIMARY KEY,
+   link_uid VARCHAR (64) NOT NULL REFERENCES shortlinks (link_uid) ON DELETE CASCADE,
+   long VARCHAR (512) NOT NULL,
+   replaced_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_shortlink_revisions_link_uid ON shortlink_revisions (link_uid, id);
@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS link_uid_seq;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS shortlinks(
+   id INTEGER PRIMARY KEY AUTOINCREMENT,
+   link_uid VARCHAR (64) NOT NULL,
+   user_uid VARCHAR (32) NULL,
+   short VARCHAR (512) NOT NULL,
+   long VARCHAR (512) NOT NULL,
+   correlation_id VARCHAR (64) NULL,
+   deleted BOOLEAN NOT NULL DEFAULT FALSE,
+   expires_at INTEGER NULL,
+   CONSTRAINT UK_shortlinks_link_uid UNIQUE (link_uid),
+   CONSTRAINT UK_shortlinks_long UNIQUE (long)
+);
+CREATE INDEX IF NOT EXISTS IX_shortlinks_user_uid ON shortlinks (user_uid);
+CREATE INDEX IF NOT EXISTS IX_shortlinks_expires_at ON shortlinks (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS clicks(
+   id INTEGER PRIMARY KEY AUTOINCREMENT,
+   link_uid VARCHAR (64) NOT NULL,
+   clicked_at INTEGER NOT NULL,
+   referrer VARCHAR (2048) NOT NULL DEFAULT '',
+   user_agent VARCHAR (512) NOT NULL DEFAULT '',
+   ip VARCHAR (64) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS IX_clicks_link_uid_clicked_at ON clicks (link_uid, clicked_at);
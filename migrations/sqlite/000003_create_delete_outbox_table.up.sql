@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS delete_outbox(
+   id INTEGER PRIMARY KEY AUTOINCREMENT,
+   user_uid VARCHAR (32) NOT NULL,
+   link_uids TEXT NOT NULL,
+   attempts INTEGER NOT NULL DEFAULT 0,
+   next_attempt_at INTEGER NOT NULL,
+   last_error TEXT NOT NULL DEFAULT '',
+   created_at INTEGER NOT NULL,
+   dead_at INTEGER NULL
+);
+CREATE INDEX IF NOT EXISTS IX_delete_outbox_next_attempt_at ON delete_outbox (next_attempt_at) WHERE dead_at IS NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS IX_delete_outbox_done_at;
+DROP INDEX IF EXISTS IX_delete_outbox_next_attempt_at;
+CREATE INDEX IF NOT EXISTS IX_delete_outbox_next_attempt_at ON delete_outbox (next_attempt_at) WHERE dead_at IS NULL;
+ALTER TABLE delete_outbox DROP COLUMN done_at;
+ALTER TABLE delete_outbox DROP COLUMN not_found;
+ALTER TABLE delete_outbox DROP COLUMN deleted_count;
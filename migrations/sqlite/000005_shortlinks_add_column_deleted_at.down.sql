@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS IX_shortlinks_deleted_at;
+ALTER TABLE shortlinks DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE shortlinks ADD COLUMN deleted_at INTEGER NULL;
+UPDATE shortlinks SET deleted_at = CAST(strftime('%s', 'now') AS INTEGER) * 1000 WHERE deleted = true AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS IX_shortlinks_deleted_at ON shortlinks (deleted_at) WHERE deleted = true;
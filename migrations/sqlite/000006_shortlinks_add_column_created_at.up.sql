@@ -0,0 +1,3 @@
+ALTER TABLE shortlinks ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+UPDATE shortlinks SET created_at = CAST(strftime('%s', 'now') AS INTEGER) * 1000;
+CREATE INDEX IF NOT EXISTS IX_shortlinks_user_uid_created_at ON shortlinks (user_uid, created_at, link_uid);
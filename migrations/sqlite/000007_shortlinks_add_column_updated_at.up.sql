@@ -0,0 +1,2 @@
+ALTER TABLE shortlinks ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+UPDATE shortlinks SET updated_at = max(created_at, coalesce(deleted_at, 0));